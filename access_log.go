@@ -0,0 +1,145 @@
+package rest
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"net/http"
+	"time"
+)
+
+/*
+AccessLog is a middleware that writes one log entry for each request handled by next, with the method, path, entity
+name, parsed QueryOptions (for GetAll), http status, response size, latency and the error returned by the repository,
+if any. It can be used with the handlers of this package, or with any handler that calls Controller methods. To
+configure it per resource, wrap each resource's handlers with the Logger they should use. Ex:
+
+	router.Get("/thing", rest.AccessLog(rest.GetAll(NewThingsRepository), logger))
+
+If the Logger is a StructuredLogger, the entries are written with LevelInfo, and the data is sent as fields.
+Otherwise, they are written with Infof, if the Logger has it (like Logrus). Loggers that only implement the Logger
+interface have no info level, so instead of logging every request to them as a warning, the entries are written to
+the default Go log package, the same as when no Logger is specified
+*/
+func AccessLog(next http.Handler, logger ...Logger) http.Handler {
+	var l Logger
+	if len(logger) > 0 {
+		l = logger[0]
+	}
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		start := time.Now()
+		entry := &accessEntry{}
+		rw := &accessResponseWriter{ResponseWriter: w}
+		next.ServeHTTP(rw, r.WithContext(context.WithValue(r.Context(), accessEntryKey{}, entry)))
+		entry.status = rw.status
+		if entry.status == 0 {
+			entry.status = http.StatusOK
+		}
+		entry.size = rw.size
+		entry.latency = time.Since(start)
+		writeAccessLog(l, r, entry)
+	})
+}
+
+// accessEntry holds the data collected by the Controller for the access log
+type accessEntry struct {
+	entity  string
+	options *QueryOptions
+	err     error
+	status  int
+	size    int64
+	latency time.Duration
+}
+
+type accessEntryKey struct{}
+
+// accessEntry returns the access log entry for the request, or nil if the request is not being logged
+func (c *Controller) accessEntry(r *http.Request) *accessEntry {
+	if r == nil {
+		return nil
+	}
+	entry, ok := r.Context().Value(accessEntryKey{}).(*accessEntry)
+	if !ok {
+		return nil
+	}
+	if entry.entity == "" && c.repository() != nil {
+		entry.entity = c.repository().EntityName()
+	}
+	return entry
+}
+
+// recordAccessError adds the error returned by the repository to the access log entry, if any
+func (c *Controller) recordAccessError(r *http.Request, err error) {
+	if entry := c.accessEntry(r); entry != nil {
+		entry.err = err
+	}
+}
+
+func writeAccessLog(logger Logger, r *http.Request, entry *accessEntry) {
+	if sl, ok := logger.(StructuredLogger); ok {
+		fields := Fields{
+			"method":  r.Method,
+			"path":    r.URL.Path,
+			"status":  entry.status,
+			"size":    entry.size,
+			"latency": entry.latency,
+		}
+		if entry.entity != "" {
+			fields["entity"] = entry.entity
+		}
+		if entry.options != nil {
+			fields["options"] = *entry.options
+		}
+		if entry.err != nil {
+			fields["error"] = entry.err.Error()
+		}
+		if requestID := r.Header.Get("X-Request-ID"); requestID != "" {
+			fields["request_id"] = requestID
+		}
+		sl.Log(r.Context(), LevelInfo, "Access", fields)
+		return
+	}
+	line := fmt.Sprintf("%s %s entity=%s status=%d size=%d latency=%s", r.Method, r.URL.RequestURI(), entry.entity,
+		entry.status, entry.size, entry.latency)
+	if entry.options != nil {
+		line += fmt.Sprintf(" options=%+v", *entry.options)
+	}
+	if entry.err != nil {
+		line += fmt.Sprintf(" error=%q", entry.err.Error())
+	}
+	if l, ok := logger.(interface{ Infof(string, ...interface{}) }); ok {
+		l.Infof("%s", line)
+		return
+	}
+	log.Print(line)
+}
+
+// accessResponseWriter records the status and the size of the response
+type accessResponseWriter struct {
+	http.ResponseWriter
+	status int
+	size   int64
+}
+
+func (w *accessResponseWriter) WriteHeader(code int) {
+	if w.status == 0 {
+		w.status = code
+	}
+	w.ResponseWriter.WriteHeader(code)
+}
+
+func (w *accessResponseWriter) Write(b []byte) (int, error) {
+	if w.status == 0 {
+		w.status = http.StatusOK
+	}
+	n, err := w.ResponseWriter.Write(b)
+	w.size += int64(n)
+	return n, err
+}
+
+// Flush implements http.Flusher, so streamed responses keep working
+func (w *accessResponseWriter) Flush() {
+	if f, ok := w.ResponseWriter.(http.Flusher); ok {
+		f.Flush()
+	}
+}
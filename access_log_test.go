@@ -0,0 +1,113 @@
+package rest_test
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"testing"
+	"time"
+
+	"github.com/deluan/rest"
+	"github.com/deluan/rest/examples"
+	"github.com/deluan/rest/logrusadapter"
+	"github.com/sirupsen/logrus"
+	"github.com/sirupsen/logrus/hooks/test"
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestAccessLog(t *testing.T) {
+	Convey("Given a GetAll handler wrapped with a Logrus StructuredLogger", t, func() {
+		l, hook := test.NewNullLogger()
+		repo := examples.NewPersistableSampleRepository(nil)
+		newRepository := func(ctx context.Context) rest.Repository { return repo }
+		handler := rest.AccessLog(rest.GetAll(newRepository, logger), logrusadapter.New(l))
+
+		Convey("When I call it", func() {
+			joe := aRecord("Joe", 30)
+			_, _ = repo.Save(&joe)
+			req, res := createRequestResponse("GET", "/sample?_sort=name", nil)
+			req.Header.Set("X-Request-ID", "abc")
+			handler.ServeHTTP(res, req)
+
+			Convey("It logs one entry with the request data", func() {
+				So(hook.Entries, ShouldHaveLength, 1)
+				entry := hook.LastEntry()
+				So(entry.Level, ShouldEqual, logrus.InfoLevel)
+				So(entry.Data["method"], ShouldEqual, "GET")
+				So(entry.Data["path"], ShouldEqual, "/sample")
+				So(entry.Data["entity"], ShouldEqual, "sample")
+				So(entry.Data["status"], ShouldEqual, 200)
+				So(entry.Data["size"], ShouldEqual, int64(res.Body.Len()))
+				So(entry.Data["latency"], ShouldHaveSameTypeAs, time.Duration(0))
+				So(entry.Data["request_id"], ShouldEqual, "abc")
+				So(entry.Data["options"].(rest.QueryOptions).Sort, ShouldEqual, "name")
+				So(entry.Data, ShouldNotContainKey, "error")
+			})
+		})
+
+		Convey("When the repository returns an error", func() {
+			repo.Error = errors.New("connection refused")
+			req, res := createRequestResponse("GET", "/sample", nil)
+			handler.ServeHTTP(res, req)
+
+			Convey("It logs the status and the error", func() {
+				entry := hook.LastEntry()
+				So(entry.Data["status"], ShouldEqual, 500)
+				So(entry.Data["error"], ShouldEqual, "connection refused")
+			})
+		})
+	})
+
+	Convey("Given a Get handler wrapped with a plain Logger with Infof", t, func() {
+		log := &infoLogger{}
+		handler, _ := createPersistableHandler(rest.Get)
+		wrapped := rest.AccessLog(handler, log)
+
+		Convey("When I call it for a missing record", func() {
+			req, res := createRequestResponse("GET", "/sample?:id=1", nil)
+			wrapped.ServeHTTP(res, req)
+
+			Convey("It logs one info line with the request data", func() {
+				So(log.infos, ShouldHaveLength, 1)
+				So(log.infos[0], ShouldStartWith, `GET /sample?:id=1 entity=sample status=404 size=34 latency=`)
+				So(log.infos[0], ShouldEndWith, `error="data not found"`)
+				So(log.warnings, ShouldBeEmpty)
+			})
+		})
+	})
+
+	Convey("Given a Get handler wrapped with a Logger without Infof", t, func() {
+		plain := &recordingLogger{}
+		handler, _ := createPersistableHandler(rest.Get)
+		wrapped := rest.AccessLog(handler, plain)
+
+		Convey("When I call it", func() {
+			var buf bytes.Buffer
+			defer log.SetOutput(log.Writer())
+			log.SetOutput(&buf)
+			req, res := createRequestResponse("GET", "/sample?:id=1", nil)
+			wrapped.ServeHTTP(res, req)
+
+			Convey("It does not log the access as a warning", func() {
+				So(res.Code, ShouldEqual, 404)
+				So(plain.warnings, ShouldBeEmpty)
+			})
+
+			Convey("It logs the access with the default Go log package", func() {
+				So(buf.String(), ShouldContainSubstring, "GET /sample?:id=1 entity=sample status=404")
+			})
+		})
+	})
+}
+
+// infoLogger is a recordingLogger that also has an info level
+type infoLogger struct {
+	recordingLogger
+	infos []string
+}
+
+func (l *infoLogger) Infof(format string, args ...interface{}) {
+	l.infos = append(l.infos, fmt.Sprintf(format, args...))
+}
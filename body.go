@@ -0,0 +1,107 @@
+package rest
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+var (
+	errBodyTooLarge = errors.New("request body too large")
+	errTrailingData = errors.New("unexpected data after the JSON value")
+)
+
+// readBody reads the request body. If the controller has a MaxBodySize, bodies bigger than it are rejected with
+// errBodyTooLarge, without reading them fully
+func (c *Controller) readBody(r *http.Request) ([]byte, error) {
+	defer r.Body.Close()
+	if c.MaxBodySize <= 0 {
+		return ioutil.ReadAll(r.Body)
+	}
+	if r.ContentLength > c.MaxBodySize {
+		return nil, errBodyTooLarge
+	}
+	body, err := ioutil.ReadAll(io.LimitReader(r.Body, c.MaxBodySize+1))
+	if err == nil && int64(len(body)) > c.MaxBodySize {
+		return nil, errBodyTooLarge
+	}
+	return body, err
+}
+
+/*
+decodeEntity decodes the body into the entity, rejecting any data after the JSON value. In StrictJSON mode, fields
+that are not present in the entity are rejected with a ValidationError listing all of them
+*/
+func (c *Controller) decodeEntity(body []byte, entity interface{}) error {
+	decoder := json.NewDecoder(bytes.NewReader(body))
+	if c.StrictJSON {
+		decoder.DisallowUnknownFields()
+	}
+	if err := decoder.Decode(entity); err != nil {
+		if name, ok := unknownField(err); ok {
+			return c.unknownFieldsError(body, name)
+		}
+		return err
+	}
+	if _, err := decoder.Token(); err != io.EOF {
+		return errTrailingData
+	}
+	return nil
+}
+
+// unknownFieldsError returns a ValidationError with all unknown fields in the body. As the decoder stops at the first
+// one, the top level fields are removed one by one, until the body is decoded without errors
+func (c *Controller) unknownFieldsError(body []byte, first string) error {
+	validation := &ValidationError{Errors: map[string]string{first: "unknown field"}}
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(body, &fields); err != nil {
+		return validation
+	}
+	name := first
+	for {
+		if _, ok := fields[name]; !ok {
+			// Nested field, can't be removed
+			return validation
+		}
+		delete(fields, name)
+		remaining, _ := json.Marshal(fields)
+		decoder := json.NewDecoder(bytes.NewReader(remaining))
+		decoder.DisallowUnknownFields()
+		var ok bool
+		if name, ok = unknownField(decoder.Decode(c.repository().NewInstance())); !ok {
+			return validation
+		}
+		validation.Errors[name] = "unknown field"
+	}
+}
+
+// unknownField returns the field name from the error returned by json.Decoder when DisallowUnknownFields is set
+func unknownField(err error) (string, bool) {
+	const prefix = "json: unknown field "
+	if err == nil || !strings.HasPrefix(err.Error(), prefix) {
+		return "", false
+	}
+	name, uerr := strconv.Unquote(strings.TrimPrefix(err.Error(), prefix))
+	return name, uerr == nil
+}
+
+// respondWithBodyError sends the response for errors returned by readBody and decodeEntity
+func (c *Controller) respondWithBodyError(w http.ResponseWriter, r *http.Request, action string, err error) {
+	var validation *ValidationError
+	switch {
+	case errors.Is(err, errBodyTooLarge):
+		c.warnf(r, "%s %s: request body bigger than %d bytes", action, c.repository().EntityName(), c.MaxBodySize)
+		c.respondWithError(w, r, http.StatusRequestEntityTooLarge, "Request body too large")
+	case errors.As(err, &validation):
+		c.warnf(r, "%s %s: %v", action, c.repository().EntityName(), err)
+		c.respondWithValidationError(w, r, validation)
+	default:
+		c.errorf(r, "%s %s %#v", action, c.repository().EntityName(), err)
+		c.respondWithError(w, r, http.StatusUnprocessableEntity, "Invalid request payload")
+	}
+}
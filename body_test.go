@@ -0,0 +1,146 @@
+package rest_test
+
+import (
+	"context"
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/deluan/rest"
+	"github.com/deluan/rest/examples"
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestController_RequestBody(t *testing.T) {
+	Convey("Given a repository with one item", t, func() {
+		repo := examples.NewPersistableSampleRepository(nil)
+		joe := aRecord("Joe", 30)
+		id, _ := repo.Save(&joe)
+		newRepository := func(ctx context.Context) rest.Repository { return repo }
+
+		Convey("And a max body size", func() {
+			handlers := rest.Configure(rest.WithLogger(logger), rest.WithMaxBodySize(40))
+
+			Convey("When I call Post with a bigger body", func() {
+				body := `{"Name":"` + strings.Repeat("x", 40) + `"}`
+				req, res := createRequestResponse("POST", "/sample", strings.NewReader(body))
+				handlers.Post(newRepository)(res, req)
+
+				Convey("It returns 413 http status", func() {
+					So(res.Code, ShouldEqual, 413)
+				})
+
+				Convey("It does not save the entity", func() {
+					count, _ := repo.Count()
+					So(count, ShouldEqual, 1)
+				})
+			})
+
+			Convey("When I call Put with a bigger body", func() {
+				body := `{"Name":"` + strings.Repeat("x", 40) + `"}`
+				req, res := createRequestResponse("PUT", "/sample?:id="+id, strings.NewReader(body))
+				handlers.Put(newRepository)(res, req)
+
+				Convey("It returns 413 http status", func() {
+					So(res.Code, ShouldEqual, 413)
+				})
+			})
+
+			Convey("When I call Put with a smaller body", func() {
+				req, res := createRequestResponse("PUT", "/sample?:id="+id, strings.NewReader(`{"ID":"`+id+`","Name":"John"}`))
+				handlers.Put(newRepository)(res, req)
+
+				Convey("It updates the entity", func() {
+					So(res.Code, ShouldEqual, 200)
+				})
+			})
+		})
+
+		Convey("When I call Post with data after the JSON value", func() {
+			req, res := createRequestResponse("POST", "/sample", strings.NewReader(`{"Name":"John"} {"Name":"Mary"}`))
+			rest.Post(newRepository, logger)(res, req)
+
+			Convey("It returns 422 http status", func() {
+				So(res.Code, ShouldEqual, 422)
+			})
+		})
+
+		Convey("When I call Put with data after the JSON value", func() {
+			req, res := createRequestResponse("PUT", "/sample?:id="+id, strings.NewReader(`{"Name":"John"}garbage`))
+			rest.Put(newRepository, logger)(res, req)
+
+			Convey("It returns 422 http status", func() {
+				So(res.Code, ShouldEqual, 422)
+			})
+		})
+
+		Convey("When I call Post with trailing whitespace", func() {
+			req, res := createRequestResponse("POST", "/sample", strings.NewReader("{\"Name\":\"John\"}\n"))
+			rest.Post(newRepository, logger)(res, req)
+
+			Convey("It saves the entity", func() {
+				So(res.Code, ShouldEqual, 200)
+			})
+		})
+
+		Convey("When I call Post with unknown fields", func() {
+			body := `{"Nmae":"John","Agee":33,"Age":33}`
+
+			Convey("And strict mode is disabled", func() {
+				req, res := createRequestResponse("POST", "/sample", strings.NewReader(body))
+				rest.Post(newRepository, logger)(res, req)
+
+				Convey("It ignores them", func() {
+					So(res.Code, ShouldEqual, 200)
+				})
+			})
+
+			Convey("And strict mode is enabled", func() {
+				req, res := createRequestResponse("POST", "/sample", strings.NewReader(body))
+				rest.Configure(rest.WithLogger(logger), rest.WithStrictJSON(true)).Post(newRepository)(res, req)
+
+				Convey("It returns 400 http status", func() {
+					So(res.Code, ShouldEqual, 400)
+				})
+
+				Convey("It lists all unknown fields", func() {
+					var validation rest.ValidationError
+					_ = json.Unmarshal(res.Body.Bytes(), &validation)
+					So(validation.Errors, ShouldResemble, map[string]string{
+						"Nmae": "unknown field",
+						"Agee": "unknown field",
+					})
+				})
+
+				Convey("It does not save the entity", func() {
+					count, _ := repo.Count()
+					So(count, ShouldEqual, 1)
+				})
+			})
+		})
+
+		Convey("When I call Put with unknown fields in strict mode", func() {
+			req, res := createRequestResponse("PUT", "/sample?:id="+id, strings.NewReader(`{"Nmae":"John"}`))
+			rest.Configure(rest.WithLogger(logger), rest.WithStrictJSON(true)).Put(newRepository)(res, req)
+
+			Convey("It returns 400 http status, with the unknown field", func() {
+				So(res.Code, ShouldEqual, 400)
+				So(res.Body.String(), ShouldContainSubstring, `"Nmae":"unknown field"`)
+			})
+
+			Convey("It does not update the entity", func() {
+				entity, _ := repo.Read(id)
+				So(entity.(examples.SampleModel).Name, ShouldEqual, "Joe")
+			})
+		})
+
+		Convey("When I call Put with only known fields in strict mode", func() {
+			req, res := createRequestResponse("PUT", "/sample?:id="+id, strings.NewReader(`{"ID":"`+id+`","Name":"John"}`))
+			rest.Configure(rest.WithLogger(logger), rest.WithStrictJSON(true)).Put(newRepository)(res, req)
+
+			Convey("It updates the entity", func() {
+				So(res.Code, ShouldEqual, 200)
+			})
+		})
+	})
+}
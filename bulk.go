@@ -0,0 +1,158 @@
+package rest
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+)
+
+// errMissingResult is reported for the items without a result, when SaveAll or DeleteAll return fewer results than
+// the items received
+var errMissingResult = errors.New("repository returned no result for the item")
+
+// BulkResult is the result of the operation for each item in BulkPost and BulkDelete responses
+type BulkResult struct {
+	// Id of the entity created or deleted
+	ID string `json:"id,omitempty"`
+
+	// Http status of the operation for this item
+	Status int `json:"status"`
+
+	// Error message, if the operation failed
+	Error string `json:"error,omitempty"`
+
+	// Validation errors, if the repository returned a ValidationError
+	Errors map[string]string `json:"errors,omitempty"`
+
+	// ID of the internal error, to match it with the logs. See ErrorSanitizer
+	CorrelationID string `json:"correlation_id,omitempty"`
+}
+
+// BulkPost handles the POST verb for a list of entities, received as a JSON array
+func (c *Controller) BulkPost(w http.ResponseWriter, r *http.Request) {
+	if !c.checkAcceptable(w, r) {
+		return
+	}
+	rp, ok := c.repository().(PersistableV2)
+	if !ok {
+		c.respondWithError(w, r, http.StatusMethodNotAllowed, "405 Method Not Allowed")
+		return
+	}
+	bodyBytes, err := c.readBody(r)
+	if err != nil {
+		c.respondWithBodyError(w, r, "reading body for", err)
+		return
+	}
+	var items []json.RawMessage
+	if err := json.Unmarshal(bodyBytes, &items); err != nil {
+		c.errorf(r, "parsing %s list %#v", c.repository().EntityName(), err)
+		c.respondWithError(w, r, http.StatusUnprocessableEntity, "Invalid request payload")
+		return
+	}
+
+	results := make([]BulkResult, len(items))
+	var entities []interface{}
+	var positions []int
+	for i, item := range items {
+		entity := c.repository().NewInstance()
+		if err := json.Unmarshal(item, entity); err != nil {
+			c.warnf(r, "parsing %s[%d] %#v", c.repository().EntityName(), i, err)
+			results[i] = BulkResult{Status: http.StatusUnprocessableEntity, Error: "Invalid request payload"}
+			continue
+		}
+		entities = append(entities, entity)
+		positions = append(positions, i)
+	}
+
+	var ids []string
+	var errs []error
+	if bp, ok := c.bulkPersistable(); ok {
+		ids, errs = bp.SaveAll(r.Context(), entities)
+	} else {
+		for _, entity := range entities {
+			id, err := rp.Save(r.Context(), entity)
+			ids = append(ids, id)
+			errs = append(errs, err)
+		}
+	}
+	for i, pos := range positions {
+		err := errorAt(errs, i)
+		if err == nil && i >= len(ids) {
+			err = errMissingResult
+		}
+		results[pos] = c.bulkResult(r, "Saving", idAt(ids, i), err)
+	}
+	c.respond(w, r, http.StatusOK, &results)
+}
+
+// BulkDelete handles the DELETE verb for a list of entities, identified by the repeated id query params
+func (c *Controller) BulkDelete(w http.ResponseWriter, r *http.Request) {
+	if !c.checkAcceptable(w, r) {
+		return
+	}
+	rp, ok := c.repository().(PersistableV2)
+	if !ok {
+		c.respondWithError(w, r, http.StatusMethodNotAllowed, "405 Method Not Allowed")
+		return
+	}
+	ids := r.URL.Query()["id"]
+	if len(ids) == 0 {
+		msg := fmt.Sprintf("Deleting %s: no ids specified", c.repository().EntityName())
+		c.warnf(r, msg)
+		c.respondWithError(w, r, http.StatusBadRequest, msg)
+		return
+	}
+
+	var errs []error
+	if bp, ok := c.bulkPersistable(); ok {
+		errs = bp.DeleteAll(r.Context(), ids)
+	} else {
+		for _, id := range ids {
+			errs = append(errs, rp.Delete(r.Context(), id))
+		}
+	}
+	results := make([]BulkResult, len(ids))
+	for i, id := range ids {
+		results[i] = c.bulkResult(r, "Deleting", id, errorAt(errs, i))
+	}
+	c.respond(w, r, http.StatusOK, &results)
+}
+
+func (c *Controller) bulkResult(r *http.Request, action string, id string, err error) BulkResult {
+	result := BulkResult{ID: id, Status: http.StatusOK}
+	if err == nil {
+		return result
+	}
+	entity := c.repository().EntityName()
+	if id != "" {
+		entity = fmt.Sprintf("%s(id:%s)", entity, id)
+	}
+	resp := c.mapError(err)
+	result.Status = resp.Status
+	if resp.internal() {
+		result.Error, result.CorrelationID = c.sanitize(r, fmt.Sprintf("%s %s", action, entity), err)
+		return result
+	}
+	c.logRepositoryError(r, resp, action, entity, err)
+	if e, ok := resp.Body.(*ValidationError); ok {
+		result.Errors = e.Errors
+	} else {
+		result.Error = resp.message(action, entity, err)
+	}
+	return result
+}
+
+func idAt(ids []string, i int) string {
+	if i < len(ids) {
+		return ids[i]
+	}
+	return ""
+}
+
+func errorAt(errs []error, i int) error {
+	if i < len(errs) {
+		return errs[i]
+	}
+	return errMissingResult
+}
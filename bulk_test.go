@@ -0,0 +1,234 @@
+package rest_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/deluan/rest"
+	"github.com/deluan/rest/examples"
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+// bulkSampleRepository implements rest.BulkPersistable, recording the calls received
+type bulkSampleRepository struct {
+	*examples.PersistableSampleRepository
+	savedAll   int
+	deletedAll int
+	short      int // Number of results to drop from the end of the slices returned
+}
+
+func (r *bulkSampleRepository) SaveAll(entities []interface{}) ([]string, []error) {
+	r.savedAll++
+	var ids []string
+	var errs []error
+	for _, e := range entities {
+		id, err := r.Save(e)
+		ids = append(ids, id)
+		errs = append(errs, err)
+	}
+	return ids[:len(ids)-r.short], errs[:len(errs)-r.short]
+}
+
+func (r *bulkSampleRepository) DeleteAll(ids []string) []error {
+	r.deletedAll++
+	var errs []error
+	for _, id := range ids {
+		errs = append(errs, r.Delete(id))
+	}
+	return errs[:len(errs)-r.short]
+}
+
+func parseBulkResults(body string) []rest.BulkResult {
+	var results []rest.BulkResult
+	if err := json.Unmarshal([]byte(body), &results); err != nil {
+		panic(err)
+	}
+	return results
+}
+
+func TestController_BulkPost(t *testing.T) {
+	Convey("Given a read-only repository", t, func() {
+		handler, _ := createReadOnlyHandler(rest.BulkPost)
+		Convey("When I send valid data", func() {
+			req, res := createRequestResponse("POST", "/sample/bulk", strings.NewReader(`[{"Name":"John"}]`))
+			handler(res, req)
+
+			Convey("It returns 405 http status", func() {
+				So(res.Code, ShouldEqual, 405)
+			})
+		})
+	})
+
+	Convey("Given an empty repository", t, func() {
+		handler, repo := createPersistableHandler(rest.BulkPost)
+
+		Convey("When I send a list of entities, one of them invalid", func() {
+			req, res := createRequestResponse("POST", "/sample/bulk",
+				strings.NewReader(`[{"Name":"John","Age":33},{"Name":123},{"Name":"Mary","Age":40}]`))
+			handler(res, req)
+
+			Convey("It returns 200 http status", func() {
+				So(res.Code, ShouldEqual, 200)
+			})
+
+			Convey("It adds the valid entities to the repository", func() {
+				count, _ := repo.Count()
+				So(count, ShouldEqual, 2)
+			})
+
+			Convey("It returns a result for each entity", func() {
+				results := parseBulkResults(res.Body.String())
+				So(results, ShouldHaveLength, 3)
+				So(results[0].Status, ShouldEqual, 200)
+				So(results[0].ID, ShouldNotBeEmpty)
+				So(results[1].Status, ShouldEqual, 422)
+				So(results[1].Error, ShouldNotBeEmpty)
+				So(results[2].Status, ShouldEqual, 200)
+				r, _ := repo.Read(results[2].ID)
+				So(r.(examples.SampleModel).Name, ShouldEqual, "Mary")
+			})
+		})
+
+		Convey("When I send invalid data", func() {
+			req, res := createRequestResponse("POST", "/sample/bulk", strings.NewReader(`{"Name":"John"}`))
+			handler(res, req)
+
+			Convey("It returns 422 http status", func() {
+				So(res.Code, ShouldEqual, 422)
+			})
+		})
+
+		Convey("When the repository returns a ValidationError", func() {
+			repo.Error = &rest.ValidationError{Errors: map[string]string{"Name": "not_valid"}}
+			req, res := createRequestResponse("POST", "/sample/bulk", strings.NewReader(`[{"Name":"John"}]`))
+			handler(res, req)
+
+			Convey("It returns the validation errors in the item result", func() {
+				results := parseBulkResults(res.Body.String())
+				So(results, ShouldHaveLength, 1)
+				So(results[0].Status, ShouldEqual, 400)
+				So(results[0].Errors, ShouldContainKey, "Name")
+			})
+		})
+	})
+
+	Convey("Given a repository that implements BulkPersistable", t, func() {
+		repo := &bulkSampleRepository{PersistableSampleRepository: examples.NewPersistableSampleRepository(nil)}
+		handler := rest.BulkPost(func(ctx context.Context) rest.Repository { return repo }, logger)
+
+		Convey("When I send a list of entities", func() {
+			req, res := createRequestResponse("POST", "/sample/bulk", strings.NewReader(`[{"Name":"John"},{"Name":"Mary"}]`))
+			handler(res, req)
+
+			Convey("It calls SaveAll once", func() {
+				So(repo.savedAll, ShouldEqual, 1)
+				count, _ := repo.Count()
+				So(count, ShouldEqual, 2)
+				So(parseBulkResults(res.Body.String()), ShouldHaveLength, 2)
+			})
+		})
+
+		Convey("When SaveAll returns fewer results than the entities received", func() {
+			repo.short = 1
+			req, res := createRequestResponse("POST", "/sample/bulk", strings.NewReader(`[{"Name":"John"},{"Name":"Mary"}]`))
+			handler(res, req)
+
+			Convey("It returns 500 http status for the items without a result", func() {
+				results := parseBulkResults(res.Body.String())
+				So(results, ShouldHaveLength, 2)
+				So(results[0].Status, ShouldEqual, 200)
+				So(results[1].Status, ShouldEqual, 500)
+				So(results[1].ID, ShouldBeEmpty)
+			})
+		})
+	})
+}
+
+func TestController_BulkDelete(t *testing.T) {
+	Convey("Given a repository with two items", t, func() {
+		handler, repo := createPersistableHandler(rest.BulkDelete)
+		joe := aRecord("Joe", 30)
+		idJoe, _ := repo.Save(&joe)
+		cecilia := aRecord("Cecilia", 22)
+		idCecilia, _ := repo.Save(&cecilia)
+
+		Convey("When I delete both and a missing one", func() {
+			req, res := createRequestResponse("DELETE", "/sample?id="+idJoe+"&id=999&id="+idCecilia, nil)
+			handler(res, req)
+
+			Convey("It returns 200 http status", func() {
+				So(res.Code, ShouldEqual, 200)
+			})
+
+			Convey("It deletes the records", func() {
+				count, _ := repo.Count()
+				So(count, ShouldEqual, 0)
+			})
+
+			Convey("It returns a result for each id", func() {
+				results := parseBulkResults(res.Body.String())
+				So(results, ShouldResemble, []rest.BulkResult{
+					{ID: idJoe, Status: 200},
+					{ID: "999", Status: 404, Error: "sample(id:999) not found"},
+					{ID: idCecilia, Status: 200},
+				})
+			})
+		})
+
+		Convey("When I do not send any ids", func() {
+			req, res := createRequestResponse("DELETE", "/sample", nil)
+			handler(res, req)
+
+			Convey("It returns 400 http status", func() {
+				So(res.Code, ShouldEqual, 400)
+			})
+		})
+
+		Convey("When the repository returns an error", func() {
+			repo.Error = errors.New("unknown error")
+			req, res := createRequestResponse("DELETE", "/sample?id="+idJoe, nil)
+			handler(res, req)
+
+			Convey("It returns the status in the item result", func() {
+				results := parseBulkResults(res.Body.String())
+				So(results[0].Status, ShouldEqual, 500)
+			})
+		})
+	})
+
+	Convey("Given a repository that implements BulkPersistable", t, func() {
+		repo := &bulkSampleRepository{PersistableSampleRepository: examples.NewPersistableSampleRepository(nil)}
+		handler := rest.BulkDelete(func(ctx context.Context) rest.Repository { return repo }, logger)
+		joe := aRecord("Joe", 30)
+		idJoe, _ := repo.Save(&joe)
+
+		Convey("When I delete a list of ids", func() {
+			req, res := createRequestResponse("DELETE", "/sample?id="+idJoe, nil)
+			handler(res, req)
+
+			Convey("It calls DeleteAll once", func() {
+				So(repo.deletedAll, ShouldEqual, 1)
+				count, _ := repo.Count()
+				So(count, ShouldEqual, 0)
+				So(parseBulkResults(res.Body.String()), ShouldResemble, []rest.BulkResult{{ID: idJoe, Status: 200}})
+			})
+		})
+
+		Convey("When DeleteAll returns fewer results than the ids received", func() {
+			repo.short = 1
+			req, res := createRequestResponse("DELETE", "/sample?id="+idJoe+"&id=999", nil)
+			handler(res, req)
+
+			Convey("It returns 500 http status for the ids without a result", func() {
+				results := parseBulkResults(res.Body.String())
+				So(results, ShouldHaveLength, 2)
+				So(results[0].Status, ShouldEqual, 200)
+				So(results[1].ID, ShouldEqual, "999")
+				So(results[1].Status, ShouldEqual, 500)
+			})
+		})
+	})
+}
@@ -0,0 +1,164 @@
+package rest
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net/http"
+	"reflect"
+	"strings"
+	"time"
+)
+
+/*
+entityTag renders the entity with the renderer negotiated for the request, returning the body, the renderer and the
+strong ETag of this representation: a hash of its version and content type if the entity is Versioned, or a hash of
+the body otherwise
+*/
+func (c *Controller) entityTag(r *http.Request, entity interface{}) ([]byte, Renderer, string, error) {
+	body, renderer, err := c.render(r, &entity)
+	if err != nil {
+		return nil, nil, "", err
+	}
+	if v, ok := entity.(Versioned); ok {
+		return body, renderer, versionTag(v.Version(), renderer.ContentType()), nil
+	}
+	return body, renderer, hashTag(body), nil
+}
+
+// versionTag returns the strong ETag for a version of an entity, represented in the content type
+func versionTag(version string, contentType string) string {
+	return hashTag([]byte(version), []byte{0}, []byte(contentType))
+}
+
+// currentTag returns the ETag of the current version of the entity, to be checked against the If-Match header. Returns
+// an empty string if the entity cannot be rendered
+func (c *Controller) currentTag(r *http.Request, entity interface{}) string {
+	_, _, etag, err := c.entityTag(r, entity)
+	if err != nil {
+		c.warnf(r, "Rendering %s: %v", c.repository().EntityName(), err)
+		return ""
+	}
+	return etag
+}
+
+// hashTag returns a strong ETag calculated from the data
+func hashTag(data ...[]byte) string {
+	h := sha256.New()
+	for _, d := range data {
+		h.Write(d)
+	}
+	return `"` + hex.EncodeToString(h.Sum(nil)[:16]) + `"`
+}
+
+// lastModified returns the modification time of the entity, if it is Timestamped. For collections, returns the latest
+// modification time of all entities, if all of them are Timestamped. Otherwise returns a zero time
+func lastModified(entity interface{}) time.Time {
+	if t, ok := entity.(Timestamped); ok {
+		return t.LastModified()
+	}
+	v := reflect.Indirect(reflect.ValueOf(entity))
+	if v.Kind() == reflect.Interface {
+		v = reflect.Indirect(v.Elem())
+	}
+	if v.Kind() != reflect.Slice && v.Kind() != reflect.Array {
+		return time.Time{}
+	}
+	var latest time.Time
+	for i := 0; i < v.Len(); i++ {
+		t, ok := v.Index(i).Interface().(Timestamped)
+		if !ok {
+			return time.Time{}
+		}
+		if modified := t.LastModified(); modified.After(latest) {
+			latest = modified
+		}
+	}
+	return latest
+}
+
+/*
+notModified sets the ETag and Last-Modified headers (if available), and checks them against the If-None-Match and
+If-Modified-Since headers of the request. If the validators match, it sends a 304 - Not Modified response and
+returns true. As specified in RFC 7232, If-Modified-Since is ignored when If-None-Match is present
+*/
+func notModified(w http.ResponseWriter, r *http.Request, etag string, modified time.Time) bool {
+	if etag != "" {
+		w.Header().Set("ETag", etag)
+	}
+	if !modified.IsZero() {
+		w.Header().Set("Last-Modified", modified.UTC().Format(http.TimeFormat))
+	}
+	if r.Method != http.MethodGet && r.Method != http.MethodHead {
+		return false
+	}
+	if ifNoneMatch := r.Header.Get("If-None-Match"); ifNoneMatch != "" {
+		if matchesIfNoneMatch(ifNoneMatch, etag) {
+			w.WriteHeader(http.StatusNotModified)
+			return true
+		}
+		return false
+	}
+	if ifModifiedSince := r.Header.Get("If-Modified-Since"); ifModifiedSince != "" && !modified.IsZero() {
+		since, err := http.ParseTime(ifModifiedSince)
+		if err == nil && !modified.Truncate(time.Second).After(since) {
+			w.WriteHeader(http.StatusNotModified)
+			return true
+		}
+	}
+	return false
+}
+
+// matchesIfNoneMatch returns true if the etag matches any of the entity tags in an If-None-Match header, using the
+// weak comparison function
+func matchesIfNoneMatch(header string, etag string) bool {
+	for _, tag := range strings.Split(header, ",") {
+		tag = strings.TrimSpace(tag)
+		if tag == "*" || (etag != "" && strings.TrimPrefix(tag, "W/") == strings.TrimPrefix(etag, "W/")) {
+			return true
+		}
+	}
+	return false
+}
+
+// matchesIfMatch returns true if the etag matches any of the entity tags in an If-Match header, using the strong
+// comparison function
+func matchesIfMatch(header string, etag string) bool {
+	for _, tag := range strings.Split(header, ",") {
+		tag = strings.TrimSpace(tag)
+		if tag == "*" || (etag != "" && tag == etag && !strings.HasPrefix(tag, "W/")) {
+			return true
+		}
+	}
+	return false
+}
+
+/*
+checkIfMatch verifies the If-Match precondition of the request against the current version of the entity. If the
+precondition fails, it sends the error response and returns false. Requests without an If-Match header always pass
+*/
+func (c *Controller) checkIfMatch(w http.ResponseWriter, r *http.Request, id string) bool {
+	ifMatch := r.Header.Get("If-Match")
+	if ifMatch == "" {
+		return true
+	}
+	current, err := c.repository().Read(r.Context(), id)
+	switch {
+	case errors.Is(err, ErrNotFound):
+		msg := fmt.Sprintf("%s(id:%s) not found", c.repository().EntityName(), id)
+		c.warnf(r, msg)
+		c.respondWithError(w, r, http.StatusPreconditionFailed, msg)
+		return false
+	case err != nil:
+		c.respondWithRepositoryError(w, r, "Reading", fmt.Sprintf("%s(id:%s)", c.repository().EntityName(), id), err)
+		return false
+	}
+	if !matchesIfMatch(ifMatch, c.currentTag(r, current)) {
+		msg := fmt.Sprintf("%s(id:%s) was modified", c.repository().EntityName(), id)
+		c.warnf(r, msg)
+		c.respondWithError(w, r, http.StatusPreconditionFailed, msg)
+		return false
+	}
+	return true
+}
@@ -0,0 +1,341 @@
+package rest_test
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/deluan/rest"
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+type versionedModel struct {
+	ID      string
+	Name    string
+	Rev     int
+	Updated time.Time
+}
+
+func (m versionedModel) Version() string {
+	return strconv.Itoa(m.Rev)
+}
+
+func (m versionedModel) LastModified() time.Time {
+	return m.Updated
+}
+
+// versionedRepository is a minimal repository for entities implementing rest.Versioned
+type versionedRepository struct {
+	data map[string]versionedModel
+}
+
+func (r *versionedRepository) Count(options ...rest.QueryOptions) (int64, error) {
+	return int64(len(r.data)), nil
+}
+
+func (r *versionedRepository) Read(id string) (interface{}, error) {
+	if m, ok := r.data[id]; ok {
+		return m, nil
+	}
+	return nil, rest.ErrNotFound
+}
+
+func (r *versionedRepository) ReadAll(options ...rest.QueryOptions) (interface{}, error) {
+	var all []versionedModel
+	for _, m := range r.data {
+		all = append(all, m)
+	}
+	return all, nil
+}
+
+func (r *versionedRepository) EntityName() string { return "versioned" }
+
+func (r *versionedRepository) NewInstance() interface{} { return &versionedModel{} }
+
+func (r *versionedRepository) Save(entity interface{}) (string, error) {
+	m := entity.(*versionedModel)
+	r.data[m.ID] = *m
+	return m.ID, nil
+}
+
+func (r *versionedRepository) Update(id string, entity interface{}, cols ...string) error {
+	m := entity.(*versionedModel)
+	current, ok := r.data[id]
+	if !ok {
+		return rest.ErrNotFound
+	}
+	if current.Rev != m.Rev {
+		return rest.ErrConflict
+	}
+	m.ID = id
+	m.Rev++
+	r.data[id] = *m
+	return nil
+}
+
+func (r *versionedRepository) Delete(id string) error {
+	delete(r.data, id)
+	return nil
+}
+
+func newVersionedRepository() *versionedRepository {
+	updated := time.Date(2020, 1, 2, 3, 4, 5, 0, time.UTC)
+	return &versionedRepository{data: map[string]versionedModel{"1": {ID: "1", Name: "Joe", Rev: 3, Updated: updated}}}
+}
+
+func versionedHandler(repo *versionedRepository, wrapper handlerWrapper) func(method, target, body, ifMatch string) (int, string) {
+	handler := wrapper(func(ctx context.Context) rest.Repository { return repo }, logger)
+	return func(method, target, body, ifMatch string) (int, string) {
+		req, res := createRequestResponse(method, target, strings.NewReader(body))
+		if ifMatch != "" {
+			req.Header.Set("If-Match", ifMatch)
+		}
+		handler(res, req)
+		return res.Code, res.Header().Get("ETag")
+	}
+}
+
+// jsonTag returns the ETag of a version of a versionedModel, rendered as JSON
+func jsonTag(version string) string {
+	return rest.VersionTag(version, rest.JSONRenderer{}.ContentType())
+}
+
+func TestController_OptimisticConcurrency(t *testing.T) {
+	Convey("Given a repository with a versioned entity", t, func() {
+		repo := newVersionedRepository()
+
+		Convey("When I call Get", func() {
+			code, etag := versionedHandler(repo, rest.Get)("GET", "/versioned?:id=1", "", "")
+
+			Convey("It returns the version in the ETag header", func() {
+				So(code, ShouldEqual, 200)
+				So(etag, ShouldEqual, jsonTag("3"))
+			})
+		})
+
+		Convey("When I call Get with another format", func() {
+			code, etag := versionedHandler(repo, rest.Get)("GET", "/versioned?:id=1&_format=csv", "", "")
+
+			Convey("It returns a different ETag for the same version", func() {
+				So(code, ShouldEqual, 200)
+				So(etag, ShouldEqual, rest.VersionTag("3", rest.CSVRenderer{}.ContentType()))
+				So(etag, ShouldNotEqual, jsonTag("3"))
+			})
+		})
+
+		Convey("When I call Put with a matching If-Match", func() {
+			code, etag := versionedHandler(repo, rest.Put)("PUT", "/versioned?:id=1", `{"Name":"John","Rev":3}`, jsonTag("3"))
+
+			Convey("It updates the entity and returns the new ETag", func() {
+				So(code, ShouldEqual, 200)
+				So(etag, ShouldEqual, jsonTag("4"))
+				So(repo.data["1"].Name, ShouldEqual, "John")
+			})
+		})
+
+		Convey("When I call Put with a stale If-Match", func() {
+			code, _ := versionedHandler(repo, rest.Put)("PUT", "/versioned?:id=1", `{"Name":"John","Rev":2}`, jsonTag("2"))
+
+			Convey("It returns 412 http status", func() {
+				So(code, ShouldEqual, 412)
+				So(repo.data["1"].Name, ShouldEqual, "Joe")
+			})
+		})
+
+		Convey("When I call Put with If-Match: *", func() {
+			code, _ := versionedHandler(repo, rest.Put)("PUT", "/versioned?:id=1", `{"Name":"John","Rev":3}`, `*`)
+
+			Convey("It updates the entity", func() {
+				So(code, ShouldEqual, 200)
+			})
+		})
+
+		Convey("When I call Put with a weak If-Match", func() {
+			code, _ := versionedHandler(repo, rest.Put)("PUT", "/versioned?:id=1", `{"Name":"John","Rev":3}`, "W/"+jsonTag("3"))
+
+			Convey("It returns 412 http status", func() {
+				So(code, ShouldEqual, 412)
+			})
+		})
+
+		Convey("When the repository detects a conflict", func() {
+			code, _ := versionedHandler(repo, rest.Put)("PUT", "/versioned?:id=1", `{"Name":"John","Rev":2}`, "")
+
+			Convey("It returns 409 http status", func() {
+				So(code, ShouldEqual, 409)
+			})
+		})
+
+		Convey("When I call Patch with a stale If-Match", func() {
+			code, _ := versionedHandler(repo, rest.Patch)("PATCH", "/versioned?:id=1", `{"Name":"John"}`, jsonTag("1")+", "+jsonTag("2"))
+
+			Convey("It returns 412 http status", func() {
+				So(code, ShouldEqual, 412)
+			})
+		})
+
+		Convey("When I call Delete with a stale If-Match", func() {
+			code, _ := versionedHandler(repo, rest.Delete)("DELETE", "/versioned?:id=1", "", jsonTag("2"))
+
+			Convey("It returns 412 http status", func() {
+				So(code, ShouldEqual, 412)
+				So(repo.data, ShouldContainKey, "1")
+			})
+		})
+
+		Convey("When I call Delete with a matching If-Match", func() {
+			code, _ := versionedHandler(repo, rest.Delete)("DELETE", "/versioned?:id=1", "", jsonTag("2")+", "+jsonTag("3"))
+
+			Convey("It deletes the entity", func() {
+				So(code, ShouldEqual, 200)
+				So(repo.data, ShouldNotContainKey, "1")
+			})
+		})
+
+		Convey("When I call Delete with If-Match for a missing entity", func() {
+			code, _ := versionedHandler(repo, rest.Delete)("DELETE", "/versioned?:id=2", "", `*`)
+
+			Convey("It returns 412 http status", func() {
+				So(code, ShouldEqual, 412)
+			})
+		})
+	})
+}
+
+func TestController_ConditionalGet(t *testing.T) {
+	Convey("Given a repository with one item", t, func() {
+		getHandler, repo := createPersistableHandler(rest.Get)
+		getAllHandler := rest.GetAll(func(ctx context.Context) rest.Repository { return repo }, logger)
+		joe := aRecord("Joe", 30)
+		id, _ := repo.Save(&joe)
+		get := func(handler http.HandlerFunc, target string, headers map[string]string) (int, http.Header, string) {
+			req, res := createRequestResponse("GET", target, nil)
+			for k, v := range headers {
+				req.Header.Set(k, v)
+			}
+			handler(res, req)
+			return res.Code, res.Header(), res.Body.String()
+		}
+
+		Convey("When I call Get", func() {
+			_, headers, _ := get(getHandler, "/sample?:id="+id, nil)
+			etag := headers.Get("ETag")
+
+			Convey("It returns an ETag calculated from the body", func() {
+				So(etag, ShouldStartWith, `"`)
+				So(len(etag), ShouldBeGreaterThan, 2)
+			})
+
+			Convey("And I call it again with If-None-Match", func() {
+				code, headers, body := get(getHandler, "/sample?:id="+id, map[string]string{"If-None-Match": etag})
+
+				Convey("It returns 304 http status, with no body", func() {
+					So(code, ShouldEqual, 304)
+					So(body, ShouldBeEmpty)
+					So(headers.Get("ETag"), ShouldEqual, etag)
+				})
+			})
+
+			Convey("And I call it again with another format", func() {
+				code, headers, _ := get(getHandler, "/sample?:id="+id+"&_format=csv", map[string]string{"If-None-Match": etag})
+
+				Convey("It returns 200 http status and the ETag of the new representation", func() {
+					So(code, ShouldEqual, 200)
+					So(headers.Get("ETag"), ShouldNotEqual, etag)
+				})
+			})
+
+			Convey("And I call it again with a weak If-None-Match", func() {
+				code, _, _ := get(getHandler, "/sample?:id="+id, map[string]string{"If-None-Match": "W/" + etag})
+
+				Convey("It returns 304 http status", func() {
+					So(code, ShouldEqual, 304)
+				})
+			})
+
+			Convey("And I call it again after the item changed", func() {
+				joe.Age = 31
+				_ = repo.Update(id, &joe)
+				code, headers, _ := get(getHandler, "/sample?:id="+id, map[string]string{"If-None-Match": etag})
+
+				Convey("It returns 200 http status and a new ETag", func() {
+					So(code, ShouldEqual, 200)
+					So(headers.Get("ETag"), ShouldNotEqual, etag)
+				})
+			})
+
+			Convey("And I call Put with the ETag in If-Match", func() {
+				putHandler := rest.Put(func(ctx context.Context) rest.Repository { return repo }, logger)
+				req, res := createRequestResponse("PUT", "/sample?:id="+id, aRecordReader(id, "John", 31))
+				req.Header.Set("If-Match", etag)
+				putHandler(res, req)
+
+				Convey("It updates the record", func() {
+					So(res.Code, ShouldEqual, 200)
+				})
+			})
+		})
+
+		Convey("When I call GetAll", func() {
+			_, headers, _ := get(getAllHandler, "/sample", nil)
+			etag := headers.Get("ETag")
+
+			Convey("And I call it again with If-None-Match", func() {
+				code, _, _ := get(getAllHandler, "/sample", map[string]string{"If-None-Match": etag})
+
+				Convey("It returns 304 http status", func() {
+					So(code, ShouldEqual, 304)
+				})
+			})
+
+			Convey("And I call it again after an item is added", func() {
+				cecilia := aRecord("Cecilia", 22)
+				_, _ = repo.Save(&cecilia)
+				code, _, _ := get(getAllHandler, "/sample", map[string]string{"If-None-Match": etag})
+
+				Convey("It returns 200 http status", func() {
+					So(code, ShouldEqual, 200)
+				})
+			})
+		})
+	})
+
+	Convey("Given a repository with a timestamped entity", t, func() {
+		repo := newVersionedRepository()
+		handler := rest.Get(func(ctx context.Context) rest.Repository { return repo }, logger)
+		get := func(ifModifiedSince string) (int, http.Header) {
+			req, res := createRequestResponse("GET", "/versioned?:id=1", nil)
+			req.Header.Set("If-Modified-Since", ifModifiedSince)
+			handler(res, req)
+			return res.Code, res.Header()
+		}
+
+		Convey("When I call Get", func() {
+			code, headers := get("")
+
+			Convey("It returns the Last-Modified header", func() {
+				So(code, ShouldEqual, 200)
+				So(headers.Get("Last-Modified"), ShouldEqual, "Thu, 02 Jan 2020 03:04:05 GMT")
+			})
+		})
+
+		Convey("When I call Get with a later If-Modified-Since", func() {
+			code, _ := get("Thu, 02 Jan 2020 03:04:05 GMT")
+
+			Convey("It returns 304 http status", func() {
+				So(code, ShouldEqual, 304)
+			})
+		})
+
+		Convey("When I call Get with an earlier If-Modified-Since", func() {
+			code, _ := get("Thu, 02 Jan 2020 03:04:04 GMT")
+
+			Convey("It returns 200 http status", func() {
+				So(code, ShouldEqual, 200)
+			})
+		})
+	})
+}
@@ -1,16 +1,14 @@
 package rest
 
 import (
-	"bytes"
+	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
-	"io/ioutil"
-	"log"
-	"math"
+	"mime"
 	"net/http"
 	"net/url"
 	"strconv"
-	"strings"
 )
 
 /*
@@ -20,98 +18,193 @@ the functions provided in the handler.go file instead of these.
 type Controller struct {
 	Repository Repository
 	Logger     Logger
+
+	// Context-aware repository. If set, it is used instead of Repository
+	RepositoryV2 RepositoryV2
+
+	// Extracts the id of the entity from the request. Defaults to reading the ":id" query param. See SetIDExtractor
+	IDExtractor IDExtractor
+
+	// If true, errors are sent as RFC 7807 Problems (application/problem+json). See EnableProblemDetails
+	ProblemDetails bool
+
+	// Mappers for the errors returned by the repository, tried before the ones registered with RegisterErrorMapper
+	ErrorMappers []ErrorMapper
+
+	// Converts internal errors before they are sent to the client. Defaults to the one set with SetErrorSanitizer
+	ErrorSanitizer ErrorSanitizer
+
+	// If greater than 0, limits the number of entities returned by GetAll. See WithMaxPageSize
+	MaxPageSize int
+
+	// Field appended to the SortFields as a tie-breaker, and used to sort in cursor mode when no sort is specified.
+	// Defaults to IDField. See WithSortTieBreaker
+	SortTieBreaker string
+
+	// If greater than 0, request bodies bigger than this (in bytes) are rejected with a 413 http status. See
+	// WithMaxBodySize
+	MaxBodySize int64
+
+	// If true, Post and Put reject entities with fields that are not present in the entity type, with a 400 http
+	// status and a ValidationError listing them. See WithStrictJSON
+	StrictJSON bool
+
+	// Renderers added with WithRenderer, used in addition to the ones registered with RegisterRenderer
+	renderers []registeredRenderer
 }
 
 // Get handles the GET verb for individual items.
 func (c *Controller) Get(w http.ResponseWriter, r *http.Request) {
-	id := r.URL.Query().Get(":id")
-	entity, err := c.Repository.Read(id)
-	switch {
-	case err == ErrNotFound:
-		msg := fmt.Sprintf("%s(id:%s) not found", c.Repository.EntityName(), id)
-		c.warnf(msg)
-		RespondWithError(w, http.StatusNotFound, msg)
+	if !c.checkAcceptable(w, r) {
 		return
-	case err == ErrPermissionDenied:
-		msg := fmt.Sprintf("Reading %s(id:%s): Permission denied", c.Repository.EntityName(), id)
-		c.warnf(msg)
-		RespondWithError(w, http.StatusForbidden, msg)
+	}
+	id := c.id(r)
+	c.debugf(r, "Reading %s(id:%s)", c.repository().EntityName(), id)
+	entity, err := c.repository().Read(r.Context(), id)
+	if err != nil {
+		c.respondWithRepositoryError(w, r, "Reading", fmt.Sprintf("%s(id:%s)", c.repository().EntityName(), id), err)
 		return
-	case err != nil:
-		c.errorf("Reading %s(id:%s): %v", c.Repository.EntityName(), id, err)
-		RespondWithError(w, http.StatusInternalServerError, err.Error())
+	}
+	body, renderer, etag, err := c.entityTag(r, entity)
+	if err != nil {
+		c.respondWithInternalError(w, r, http.StatusInternalServerError, "Rendering "+c.repository().EntityName(), err)
+		return
+	}
+	if notModified(w, r, etag, lastModified(entity)) {
 		return
 	}
-	RespondWithJSON(w, http.StatusOK, &entity)
+	writeResponse(w, http.StatusOK, renderer.ContentType(), body)
 }
 
 // GetAll handles the GET verb for the full collection
 func (c *Controller) GetAll(w http.ResponseWriter, r *http.Request) {
-	options := c.parseOptions(r.URL.Query())
-	entities, err := c.Repository.ReadAll(options)
-	if err == ErrPermissionDenied {
-		msg := fmt.Sprintf("Error reading %s: Permission denied", c.Repository.EntityName())
-		c.warnf(msg)
-		RespondWithError(w, http.StatusForbidden, msg)
+	if !c.checkAcceptable(w, r) {
+		return
+	}
+	params := r.URL.Query()
+	options := c.parseOptions(r, params)
+	c.debugf(r, "Reading %s with options %+v", c.repository().EntityName(), options)
+	if entry := c.accessEntry(r); entry != nil {
+		entry.options = &options
+	}
+	if renderer, ok := c.streamRenderer(r); ok {
+		count, err := c.repository().Count(r.Context(), options)
+		if err != nil {
+			c.respondReadAllError(w, r, err)
+			return
+		}
+		w.Header().Set("X-Total-Count", strconv.FormatInt(count, 10))
+		if links := paginationLinks(r, options, count, "", false); links != "" {
+			w.Header().Set("Link", links)
+		}
+		c.stream(w, r, renderer, options)
+		return
+	}
+	entities, next, cursorMode, err := c.readAll(r.Context(), params, &options)
+	if err != nil {
+		c.respondReadAllError(w, r, err)
+		return
+	}
+	var token string
+	if next != nil {
+		token, err = encodeCursor(next)
+		if err != nil {
+			c.respondWithInternalError(w, r, http.StatusInternalServerError, "Encoding cursor for "+c.repository().EntityName(), err)
+			return
+		}
+		w.Header().Set("X-Next-Cursor", token)
+	}
+	// In cursor mode, counting all entities in every page would bring back the cost cursors are meant to avoid
+	var count int64
+	if !cursorMode {
+		count, err = c.repository().Count(r.Context(), options)
+		if err != nil {
+			c.respondReadAllError(w, r, err)
+			return
+		}
+		w.Header().Set("X-Total-Count", strconv.FormatInt(count, 10))
+	}
+	if links := paginationLinks(r, options, count, token, cursorMode); links != "" {
+		w.Header().Set("Link", links)
+	}
+	body, renderer, err := c.render(r, &entities)
+	if err != nil {
+		c.respondWithInternalError(w, r, http.StatusInternalServerError, "Rendering "+c.repository().EntityName(), err)
 		return
 	}
+	etag := hashTag(body, []byte(w.Header().Get("X-Total-Count")))
+	if notModified(w, r, etag, lastModified(entities)) {
+		return
+	}
+	writeResponse(w, http.StatusOK, renderer.ContentType(), body)
+}
+
+func (c *Controller) respondReadAllError(w http.ResponseWriter, r *http.Request, err error) {
+	if err == errInvalidCursor {
+		msg := fmt.Sprintf("Error reading %s: Invalid cursor", c.repository().EntityName())
+		c.warnf(r, msg)
+		c.respondWithError(w, r, http.StatusBadRequest, msg)
+		return
+	}
+	c.respondWithRepositoryError(w, r, "Reading", c.repository().EntityName(), err)
+}
+
+// readAll uses cursor-based pagination if the repository supports it and the client requested it (sending a _cursor
+// param). Otherwise, it falls back to offset pagination. cursorMode reports which one was used
+func (c *Controller) readAll(ctx context.Context, params url.Values, options *QueryOptions) (entities interface{},
+	next Cursor, cursorMode bool, err error) {
+	cp, ok := c.cursorPaginated()
+	if _, requested := params["_cursor"]; !ok || !requested {
+		entities, err = c.repository().ReadAll(ctx, *options)
+		return entities, nil, false, err
+	}
+	cursor, err := decodeCursor(params.Get("_cursor"))
 	if err != nil {
-		c.errorf("Error reading %s: %v", c.Repository.EntityName(), err)
-		RespondWithError(w, http.StatusInternalServerError, err.Error())
+		return nil, nil, true, err
 	}
-	count, _ := c.Repository.Count(options)
-	w.Header().Set("X-Total-Count", strconv.FormatInt(count, 10))
-	RespondWithJSON(w, http.StatusOK, &entities)
+	// Keyset pagination requires a deterministic order
+	if len(options.SortFields) == 0 {
+		options.SortFields = []SortField{{Field: c.sortTieBreaker()}}
+	}
+	options.Offset = 0
+	entities, next, err = cp.ReadAllAfter(ctx, cursor, *options)
+	return entities, next, true, err
 }
 
 // Put handles the PUT verb
 func (c *Controller) Put(w http.ResponseWriter, r *http.Request) {
-	rp, ok := c.Repository.(Persistable)
+	if !c.checkAcceptable(w, r) {
+		return
+	}
+	rp, ok := c.repository().(PersistableV2)
 	if !ok {
-		RespondWithError(w, http.StatusMethodNotAllowed, "405 Method Not Allowed")
+		c.respondWithError(w, r, http.StatusMethodNotAllowed, "405 Method Not Allowed")
 		return
 	}
-	bodyBytes, err := ioutil.ReadAll(r.Body)
+	bodyBytes, err := c.readBody(r)
 	if err != nil {
-		c.errorf("reading body for %s %#v", c.Repository.EntityName(), err)
-		RespondWithError(w, http.StatusUnprocessableEntity, "Invalid request payload")
+		c.respondWithBodyError(w, r, "reading body for", err)
 		return
 	}
-	r.Body.Close()
-	entity := c.Repository.NewInstance()
-	decoder := json.NewDecoder(ioutil.NopCloser(bytes.NewBuffer(bodyBytes)))
-	if err := decoder.Decode(entity); err != nil {
-		c.errorf("parsing %s %#v", c.Repository.EntityName(), err)
-		RespondWithError(w, http.StatusUnprocessableEntity, "Invalid request payload")
+	entity := c.repository().NewInstance()
+	if err := c.decodeEntity(bodyBytes, entity); err != nil {
+		c.respondWithBodyError(w, r, "parsing", err)
 		return
 	}
 	fields, err := c.getFieldNames(bodyBytes)
 	if err != nil {
-		c.errorf("parsing %s %#v", c.Repository.EntityName(), err)
-		RespondWithError(w, http.StatusUnprocessableEntity, "Invalid request payload")
+		c.errorf(r, "parsing %s %#v", c.repository().EntityName(), err)
+		c.respondWithError(w, r, http.StatusUnprocessableEntity, "Invalid request payload")
 		return
 	}
-	id := r.URL.Query().Get(":id")
-	err = rp.Update(id, entity, fields...)
-	switch {
-	case err == ErrNotFound:
-		msg := fmt.Sprintf("%s not found", c.Repository.EntityName())
-		c.warnf(msg)
-		RespondWithError(w, http.StatusNotFound, msg)
-		return
-	case err == ErrPermissionDenied:
-		msg := fmt.Sprintf("Updating %s: Permission denied", c.Repository.EntityName())
-		c.warnf(msg)
-		RespondWithError(w, http.StatusForbidden, msg)
+	id := c.id(r)
+	if !c.checkIfMatch(w, r, id) {
 		return
-	case err != nil:
-		if e, ok := err.(*ValidationError); ok {
-			c.warnf("Updating %s: %v", c.Repository.EntityName(), e.Error())
-			RespondWithJSON(w, http.StatusBadRequest, e)
-		} else {
-			c.errorf("Updating %s: %v", c.Repository.EntityName(), err)
-			RespondWithError(w, http.StatusInternalServerError, err.Error())
-		}
+	}
+	c.debugf(r, "Updating %s(id:%s), fields: %v", c.repository().EntityName(), id, fields)
+	err = rp.Update(r.Context(), id, entity, fields...)
+	if err != nil {
+		c.respondWithRepositoryError(w, r, "Updating", fmt.Sprintf("%s(id:%s)", c.repository().EntityName(), id), err)
 		return
 	}
 	c.Get(w, r)
@@ -129,118 +222,145 @@ func (c *Controller) getFieldNames(bytes []byte) ([]string, error) {
 	return fields, nil
 }
 
-// Post handles the POST verb
-func (c *Controller) Post(w http.ResponseWriter, r *http.Request) {
-	rp, ok := c.Repository.(Persistable)
+// Patch handles the PATCH verb, applying a JSON Patch (RFC 6902) or a JSON Merge Patch (RFC 7396) to the entity,
+// depending on the request's Content-Type
+func (c *Controller) Patch(w http.ResponseWriter, r *http.Request) {
+	if !c.checkAcceptable(w, r) {
+		return
+	}
+	rp, ok := c.repository().(PersistableV2)
 	if !ok {
-		RespondWithError(w, http.StatusMethodNotAllowed, "405 Method Not Allowed")
+		c.respondWithError(w, r, http.StatusMethodNotAllowed, "405 Method Not Allowed")
+		return
+	}
+	mediaType, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	if mediaType != "" && mediaType != mediaTypeMergePatch && mediaType != mediaTypeJSONPatch && mediaType != mediaTypeJSON {
+		msg := fmt.Sprintf("Unsupported patch format: %s", mediaType)
+		c.warnf(r, "Patching %s: %s", c.repository().EntityName(), msg)
+		c.respondWithError(w, r, http.StatusUnsupportedMediaType, msg)
+		return
+	}
+	bodyBytes, err := c.readBody(r)
+	if err != nil {
+		c.respondWithBodyError(w, r, "reading body for", err)
 		return
 	}
-	entity := c.Repository.NewInstance()
-	decoder := json.NewDecoder(r.Body)
-	if err := decoder.Decode(entity); err != nil {
-		c.errorf("parsing %s %#v", c.Repository.EntityName(), err)
-		RespondWithError(w, http.StatusUnprocessableEntity, "Invalid request payload")
+	var patch interface{}
+	if err := decodeJSON(bodyBytes, &patch); err != nil {
+		c.errorf(r, "parsing patch for %s %#v", c.repository().EntityName(), err)
+		c.respondWithError(w, r, http.StatusUnprocessableEntity, "Invalid request payload")
 		return
 	}
-	id, err := rp.Save(entity)
+	id := c.id(r)
+	current, err := c.repository().Read(r.Context(), id)
+	if err != nil {
+		c.respondWithRepositoryError(w, r, "Reading", fmt.Sprintf("%s(id:%s)", c.repository().EntityName(), id), err)
+		return
+	}
+	if ifMatch := r.Header.Get("If-Match"); ifMatch != "" && !matchesIfMatch(ifMatch, c.currentTag(r, current)) {
+		msg := fmt.Sprintf("%s(id:%s) was modified", c.repository().EntityName(), id)
+		c.warnf(r, msg)
+		c.respondWithError(w, r, http.StatusPreconditionFailed, msg)
+		return
+	}
+	doc, err := toDocument(current)
+	if err != nil {
+		c.respondWithInternalError(w, r, http.StatusInternalServerError,
+			fmt.Sprintf("Converting %s(id:%s)", c.repository().EntityName(), id), err)
+		return
+	}
+	patched, fields, err := applyPatch(mediaType, doc, patch)
 	switch {
-	case err == ErrPermissionDenied:
-		msg := fmt.Sprintf("Saving %s: Permission denied", c.Repository.EntityName())
-		c.warnf(msg)
-		RespondWithError(w, http.StatusForbidden, msg)
+	case errors.Is(err, errPatchTestFailed):
+		msg := fmt.Sprintf("Patching %s(id:%s): %v", c.repository().EntityName(), id, err)
+		c.warnf(r, msg)
+		c.respondWithError(w, r, http.StatusConflict, msg)
 		return
 	case err != nil:
-		if e, ok := err.(*ValidationError); ok {
-			c.warnf("Saving %s: %v", c.Repository.EntityName(), e.Error())
-			RespondWithJSON(w, http.StatusBadRequest, e)
-		} else {
-			c.errorf("Saving %s: %v", c.Repository.EntityName(), err)
-			RespondWithError(w, http.StatusInternalServerError, err.Error())
-		}
+		msg := fmt.Sprintf("Patching %s(id:%s): %v", c.repository().EntityName(), id, err)
+		c.warnf(r, msg)
+		c.respondWithError(w, r, http.StatusUnprocessableEntity, msg)
+		return
+	}
+	if len(fields) == 0 {
+		c.Get(w, r)
 		return
 	}
-	RespondWithJSON(w, http.StatusOK, &map[string]string{"id": id})
+	entity := c.repository().NewInstance()
+	if err := fromDocument(patched, entity); err != nil {
+		c.errorf(r, "parsing patched %s %#v", c.repository().EntityName(), err)
+		c.respondWithError(w, r, http.StatusUnprocessableEntity, "Invalid request payload")
+		return
+	}
+	c.debugf(r, "Updating %s(id:%s), fields: %v", c.repository().EntityName(), id, fields)
+	err = rp.Update(r.Context(), id, entity, fields...)
+	if err != nil {
+		c.respondWithRepositoryError(w, r, "Updating", fmt.Sprintf("%s(id:%s)", c.repository().EntityName(), id), err)
+		return
+	}
+	c.Get(w, r)
 }
 
-// Delete handles the DELETE verb
-func (c *Controller) Delete(w http.ResponseWriter, r *http.Request) {
-	rp, ok := c.Repository.(Persistable)
+// Post handles the POST verb
+func (c *Controller) Post(w http.ResponseWriter, r *http.Request) {
+	if !c.checkAcceptable(w, r) {
+		return
+	}
+	rp, ok := c.repository().(PersistableV2)
 	if !ok {
-		RespondWithError(w, http.StatusMethodNotAllowed, "405 Method Not Allowed")
+		c.respondWithError(w, r, http.StatusMethodNotAllowed, "405 Method Not Allowed")
 		return
 	}
-	id := r.URL.Query().Get(":id")
-	err := rp.Delete(id)
-	switch {
-	case err == ErrNotFound:
-		msg := fmt.Sprintf("%s(id:%s) not found", c.Repository.EntityName(), id)
-		c.warnf(msg)
-		RespondWithError(w, http.StatusNotFound, msg)
+	bodyBytes, err := c.readBody(r)
+	if err != nil {
+		c.respondWithBodyError(w, r, "reading body for", err)
 		return
-	case err == ErrPermissionDenied:
-		msg := fmt.Sprintf("Deleting %s(id:%s): Permission denied", c.Repository.EntityName(), id)
-		c.warnf(msg)
-		RespondWithError(w, http.StatusForbidden, msg)
+	}
+	entity := c.repository().NewInstance()
+	if err := c.decodeEntity(bodyBytes, entity); err != nil {
+		c.respondWithBodyError(w, r, "parsing", err)
 		return
-	case err != nil:
-		c.errorf("Deleting %s(id:%s): %v", c.Repository.EntityName(), id, err)
-		RespondWithError(w, http.StatusInternalServerError, err.Error())
+	}
+	c.debugf(r, "Saving %s", c.repository().EntityName())
+	id, err := rp.Save(r.Context(), entity)
+	if err != nil {
+		c.respondWithRepositoryError(w, r, "Saving", c.repository().EntityName(), err)
 		return
 	}
-	RespondWithJSON(w, http.StatusOK, &map[string]string{})
+	c.respond(w, r, http.StatusOK, &map[string]string{"id": id})
 }
 
-func (c *Controller) parseFilters(params url.Values) map[string]interface{} {
-	var filterStr = params.Get("_filters")
-	filters := make(map[string]interface{})
-	if filterStr != "" {
-		filterStr, _ = url.QueryUnescape(filterStr)
-		if err := json.Unmarshal([]byte(filterStr), &filters); err != nil {
-			c.warnf("Invalid filter specification: %s - %v", filterStr, err)
-		}
+// Delete handles the DELETE verb
+func (c *Controller) Delete(w http.ResponseWriter, r *http.Request) {
+	if !c.checkAcceptable(w, r) {
+		return
 	}
-	for k, v := range params {
-		if strings.HasPrefix(k, "_") {
-			continue
-		}
-		if len(v) == 1 {
-			filters[k] = v[0]
-		} else {
-			filters[k] = v
-		}
+	rp, ok := c.repository().(PersistableV2)
+	if !ok {
+		c.respondWithError(w, r, http.StatusMethodNotAllowed, "405 Method Not Allowed")
+		return
 	}
-	return filters
+	id := c.id(r)
+	if !c.checkIfMatch(w, r, id) {
+		return
+	}
+	c.debugf(r, "Deleting %s(id:%s)", c.repository().EntityName(), id)
+	err := rp.Delete(r.Context(), id)
+	if err != nil {
+		c.respondWithRepositoryError(w, r, "Deleting", fmt.Sprintf("%s(id:%s)", c.repository().EntityName(), id), err)
+		return
+	}
+	c.respond(w, r, http.StatusOK, &map[string]string{})
 }
 
-func (c *Controller) parseOptions(params url.Values) QueryOptions {
-	start, _ := strconv.Atoi(params.Get("_start"))
-	end, _ := strconv.Atoi(params.Get("_end"))
-
-	sortField := params.Get("_sort")
-	sortDir := params.Get("_order")
-
-	return QueryOptions{
-		Sort:    sortField,
-		Order:   strings.ToLower(sortDir),
-		Offset:  start,
-		Max:     int(math.Max(0, float64(end-start))),
-		Filters: c.parseFilters(params),
-	}
+func (c *Controller) debugf(r *http.Request, format string, args ...interface{}) {
+	c.logf(r, LevelDebug, format, args...)
 }
 
-func (c *Controller) warnf(format string, args ...interface{}) {
-	if c.Logger != nil {
-		c.Logger.Warnf(format, args...)
-	} else {
-		log.Printf(format, args...)
-	}
+func (c *Controller) warnf(r *http.Request, format string, args ...interface{}) {
+	c.logf(r, LevelWarn, format, args...)
 }
 
-func (c *Controller) errorf(format string, args ...interface{}) {
-	if c.Logger != nil {
-		c.Logger.Errorf(format, args...)
-	} else {
-		log.Printf(format, args...)
-	}
+func (c *Controller) errorf(r *http.Request, format string, args ...interface{}) {
+	c.logf(r, LevelError, format, args...)
 }
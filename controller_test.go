@@ -112,6 +112,126 @@ func TestController_GetAll(t *testing.T) {
 				Convey("It returns 2 in the X-Total-Count header", func() {
 					So(res.Header()["X-Total-Count"][0], ShouldEqual, "2")
 				})
+
+				Convey("It does not return a Link header", func() {
+					So(res.Header().Get("Link"), ShouldBeEmpty)
+				})
+			})
+
+			Convey("And I call GetAll with pagination", func() {
+				req, res := createRequestResponse("GET", "/sample?_page=1&_limit=1", nil)
+				handler(res, req)
+
+				Convey("It returns the Link header", func() {
+					So(res.Header().Get("Link"), ShouldEqual,
+						`</sample?_page=1&_limit=1>; rel="first", `+
+							`</sample?_page=2&_limit=1>; rel="next", `+
+							`</sample?_page=2&_limit=1>; rel="last"`)
+				})
+			})
+
+			Convey("And I call GetAll sorting by age", func() {
+				req, res := createRequestResponse("GET", "/sample?_sort=age&_order=desc", nil)
+				handler(res, req)
+
+				Convey("It returns the records in the requested order", func() {
+					response := make([]examples.SampleModel, 0)
+					if err := json.Unmarshal([]byte(res.Body.String()), &response); err != nil {
+						panic(err)
+					}
+					So(response, ShouldHaveLength, 2)
+					So(response[0].ID, ShouldEqual, idJoe)
+					So(response[1].ID, ShouldEqual, idCecilia)
+				})
+			})
+
+			Convey("And I call GetAll in cursor mode", func() {
+				req, res := createRequestResponse("GET", "/sample?_cursor=&_limit=1&_sort=age", nil)
+				handler(res, req)
+
+				Convey("It returns the first page", func() {
+					response := make([]examples.SampleModel, 0)
+					if err := json.Unmarshal([]byte(res.Body.String()), &response); err != nil {
+						panic(err)
+					}
+					So(response, ShouldHaveLength, 1)
+					So(response[0].ID, ShouldEqual, idCecilia)
+				})
+
+				Convey("It returns the next cursor in the X-Next-Cursor header", func() {
+					So(res.Header().Get("X-Next-Cursor"), ShouldNotBeEmpty)
+				})
+
+				Convey("It does not count the entities", func() {
+					So(res.Header().Get("X-Total-Count"), ShouldBeEmpty)
+				})
+
+				Convey("And I call GetAll with the next cursor", func() {
+					next := res.Header().Get("X-Next-Cursor")
+					req, res := createRequestResponse("GET", "/sample?_limit=1&_sort=age&_cursor="+next, nil)
+					handler(res, req)
+
+					Convey("It returns the second page", func() {
+						response := make([]examples.SampleModel, 0)
+						if err := json.Unmarshal([]byte(res.Body.String()), &response); err != nil {
+							panic(err)
+						}
+						So(response, ShouldHaveLength, 1)
+						So(response[0].ID, ShouldEqual, idJoe)
+					})
+
+					Convey("It does not return a next cursor", func() {
+						So(res.Header().Get("X-Next-Cursor"), ShouldBeEmpty)
+					})
+				})
+			})
+
+			Convey("And I call GetAll with an invalid cursor", func() {
+				req, res := createRequestResponse("GET", "/sample?_cursor=INVALID", nil)
+				handler(res, req)
+
+				Convey("It returns 400 http status", func() {
+					So(res.Code, ShouldEqual, 400)
+				})
+			})
+
+			Convey("And I call GetAll with a search term", func() {
+				req, res := createRequestResponse("GET", "/sample?q=CECI", nil)
+				handler(res, req)
+
+				Convey("It returns only the matching records", func() {
+					response := make([]examples.SampleModel, 0)
+					if err := json.Unmarshal([]byte(res.Body.String()), &response); err != nil {
+						panic(err)
+					}
+					So(response, ShouldHaveLength, 1)
+					So(response[0].ID, ShouldEqual, idCecilia)
+				})
+
+				Convey("It returns the number of matching records in the X-Total-Count header", func() {
+					So(res.Header()["X-Total-Count"][0], ShouldEqual, "1")
+				})
+			})
+			Convey("And I call GetAll accepting CSV", func() {
+				req, res := createRequestResponse("GET", "/sample?_sort=age", nil)
+				req.Header.Set("Accept", "text/csv, application/json;q=0.5")
+				handler(res, req)
+
+				Convey("It returns the records as CSV", func() {
+					So(res.Code, ShouldEqual, 200)
+					So(res.Header().Get("Content-Type"), ShouldEqual, "text/csv")
+					So(res.Body.String(), ShouldEqual, "ID,Name,Age\n"+idCecilia+",Cecilia,22\n"+idJoe+",Joe,30\n")
+				})
+			})
+
+			Convey("And I call GetAll accepting an unsupported media type", func() {
+				req, res := createRequestResponse("GET", "/sample", nil)
+				req.Header.Set("Accept", "text/html")
+				handler(res, req)
+
+				Convey("It returns 406 http status", func() {
+					So(res.Code, ShouldEqual, 406)
+				})
 			})
 		})
 
@@ -385,6 +505,10 @@ func TestController_Put(t *testing.T) {
 					So(response.Name, ShouldEqual, "John")
 					So(response.Age, ShouldEqual, 31)
 				})
+
+				Convey("It adds Accept to the Vary header only once", func() {
+					So(res.Header()["Vary"], ShouldResemble, []string{"Accept"})
+				})
 			})
 		})
 
@@ -441,6 +565,138 @@ func TestController_Put(t *testing.T) {
 	})
 }
 
+func TestController_Patch(t *testing.T) {
+	Convey("Given a read-only repository", t, func() {
+		handler, _ := createReadOnlyHandler(rest.Patch)
+		Convey("When I call Patch id=1", func() {
+			req, res := createRequestResponse("PATCH", "/sample?:id=1", strings.NewReader(`{"Name":"John"}`))
+			handler(res, req)
+
+			Convey("It returns 405 http status", func() {
+				So(res.Code, ShouldEqual, 405)
+			})
+		})
+	})
+
+	Convey("Given an empty repository", t, func() {
+		handler, repo := createPersistableHandler(rest.Patch)
+
+		Convey("When I call Patch id=1", func() {
+			req, res := createRequestResponse("PATCH", "/sample?:id=1", strings.NewReader(`{"Name":"John"}`))
+			handler(res, req)
+
+			Convey("It returns 404 http status", func() {
+				So(res.Code, ShouldEqual, 404)
+			})
+		})
+
+		Convey("When an item is added", func() {
+			joe := aRecord("Joe", 30)
+			id, _ := repo.Save(&joe)
+
+			Convey("And I call Patch with a merge patch", func() {
+				req, res := createRequestResponse("PATCH", "/sample?:id="+id, strings.NewReader(`{"Name":"John"}`))
+				req.Header.Set("Content-Type", "application/merge-patch+json")
+				handler(res, req)
+
+				Convey("It returns 200 http status", func() {
+					So(res.Code, ShouldEqual, 200)
+				})
+
+				Convey("It updates only the patched fields", func() {
+					var response examples.SampleModel
+					if err := json.Unmarshal([]byte(res.Body.String()), &response); err != nil {
+						panic(err)
+					}
+					So(response.ID, ShouldEqual, id)
+					So(response.Name, ShouldEqual, "John")
+					So(response.Age, ShouldEqual, 30)
+				})
+
+				Convey("It passes the changed fields to the repository", func() {
+					So(repo.UpdatedCols, ShouldResemble, []string{"Name"})
+				})
+			})
+
+			Convey("And I call Patch with a JSON Patch", func() {
+				req, res := createRequestResponse("PATCH", "/sample?:id="+id, strings.NewReader(
+					`[{"op":"test","path":"/Name","value":"Joe"},{"op":"replace","path":"/Age","value":31}]`))
+				req.Header.Set("Content-Type", "application/json-patch+json")
+				handler(res, req)
+
+				Convey("It returns 200 http status", func() {
+					So(res.Code, ShouldEqual, 200)
+				})
+
+				Convey("It updates the record, passing the changed fields to the repository", func() {
+					r, _ := repo.Read(id)
+					So(r.(examples.SampleModel).Age, ShouldEqual, 31)
+					So(repo.UpdatedCols, ShouldResemble, []string{"Age"})
+				})
+			})
+
+			Convey("And I call Patch with a failing JSON Patch test", func() {
+				req, res := createRequestResponse("PATCH", "/sample?:id="+id, strings.NewReader(
+					`[{"op":"test","path":"/Name","value":"John"},{"op":"replace","path":"/Age","value":31}]`))
+				req.Header.Set("Content-Type", "application/json-patch+json")
+				handler(res, req)
+
+				Convey("It returns 409 http status", func() {
+					So(res.Code, ShouldEqual, 409)
+				})
+
+				Convey("It does not update the record", func() {
+					r, _ := repo.Read(id)
+					So(r.(examples.SampleModel).Age, ShouldEqual, 30)
+				})
+			})
+
+			Convey("And I call Patch with an invalid JSON Patch operation", func() {
+				req, res := createRequestResponse("PATCH", "/sample?:id="+id, strings.NewReader(
+					`[{"op":"remove","path":"/Missing"}]`))
+				req.Header.Set("Content-Type", "application/json-patch+json")
+				handler(res, req)
+
+				Convey("It returns 422 http status", func() {
+					So(res.Code, ShouldEqual, 422)
+				})
+			})
+
+			Convey("And I call Patch with a null value", func() {
+				req, res := createRequestResponse("PATCH", "/sample?:id="+id, strings.NewReader(`{"Age":null}`))
+				req.Header.Set("Content-Type", "application/merge-patch+json")
+				handler(res, req)
+
+				Convey("It removes the field", func() {
+					So(res.Code, ShouldEqual, 200)
+					r, _ := repo.Read(id)
+					So(r.(examples.SampleModel).Age, ShouldEqual, 0)
+					So(r.(examples.SampleModel).Name, ShouldEqual, "Joe")
+				})
+			})
+
+			Convey("And I call Patch with an unsupported content type", func() {
+				req, res := createRequestResponse("PATCH", "/sample?:id="+id, strings.NewReader(`Name=John`))
+				req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+				handler(res, req)
+
+				Convey("It returns 415 http status", func() {
+					So(res.Code, ShouldEqual, 415)
+				})
+			})
+
+			Convey("And I call Patch with an invalid patch", func() {
+				req, res := createRequestResponse("PATCH", "/sample?:id="+id, strings.NewReader(`["Name"]`))
+				handler(res, req)
+
+				Convey("It returns 422 http status", func() {
+					So(res.Code, ShouldEqual, 422)
+				})
+			})
+		})
+	})
+}
+
 func TestController_Post(t *testing.T) {
 	Convey("Given a read-only repository", t, func() {
 		handler, _ := createReadOnlyHandler(rest.Delete)
@@ -0,0 +1,58 @@
+package rest
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+)
+
+/*
+Cursor is the sort key of the last record returned in a page, used for cursor-based (keyset) pagination. Keys are
+the names of the fields in QueryOptions.SortFields, and values are the values of these fields in the last record.
+When decoded from a request, numeric values are represented as json.Number.
+*/
+type Cursor map[string]interface{}
+
+/*
+CursorPaginated can be implemented by repositories in addition to the Repository interface, to enable cursor-based
+pagination in GetAll. Cursor mode is activated by the client, sending a _cursor param (empty for the first page).
+The next cursor is returned to the client in the X-Next-Cursor header, and it is omitted when there are no more pages.
+As counting all entities is expensive in the large tables cursors are meant for, the X-Total-Count header is not
+sent in cursor mode.
+Repositories that do not implement this interface ignore the _cursor param, and keep using Offset. See also
+CursorPaginatedV2.
+*/
+type CursorPaginated interface {
+	// Returns up to options.Max entities (all if Max is 0) that come after the cursor, in the order specified by
+	// options.SortFields. For the first page, cursor is nil. It also returns the Cursor of the last entity
+	// returned, or nil if there are no more entities
+	ReadAllAfter(cursor Cursor, options QueryOptions) (interface{}, Cursor, error)
+}
+
+var errInvalidCursor = errors.New("invalid cursor")
+
+func encodeCursor(cursor Cursor) (string, error) {
+	buf, err := json.Marshal(cursor)
+	if err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(buf), nil
+}
+
+func decodeCursor(token string) (Cursor, error) {
+	if token == "" {
+		return nil, nil
+	}
+	buf, err := base64.RawURLEncoding.DecodeString(token)
+	if err != nil {
+		return nil, errInvalidCursor
+	}
+	var cursor Cursor
+	decoder := json.NewDecoder(bytes.NewReader(buf))
+	decoder.UseNumber()
+	if err := decoder.Decode(&cursor); err != nil || cursor == nil {
+		return nil, errInvalidCursor
+	}
+	return cursor, nil
+}
@@ -6,13 +6,18 @@ need a simple REST server for your data.
 To use it, you will need to provide an implementation of the Repository interface and a function to create
 such repository.
 
-The controller was created to be used with Gorilla Pat, as it requires URL params to be parsed and set
-as query params. You can easily adapt it to work with other routers and frameworks using a custom middleware.
+The controller was created to be used with Gorilla Pat, as by default it reads the id of the entity from the ":id" query
+param. To use it with other routers, set an IDExtractor with SetIDExtractor. Extractors for chi, gorilla/mux and the
+Go 1.22 http.ServeMux are provided: ChiID, MuxID and PathValueID.
 
 The functionality is provided by a set of handlers named after the REST verbs they handle: Get(), GetAll(), Put(),
-Post() and Delete(). Each of these functions receive a function used to construct your repository, and an optional
-implementation of Logger (compatible with Logrus). If no Logger is specified, the functions falls back to the default
-Go log package
+Patch(), Post() and Delete(). Each of these functions receive a function used to construct your repository, and an
+optional implementation of Logger (compatible with Logrus). If no Logger is specified, the functions falls back to the
+default Go log package. BulkPost() and BulkDelete() handlers are also provided, to create and delete multiple entities
+in a single request. To serve all verbs for an entity with a single http.Handler, use Resource()
+
+To configure each resource without changing the package defaults, create the handlers with Configure() and a set of
+Options (ex: WithMaxPageSize, WithErrorMapper, WithRenderer). The same options can be used with NewController
 
 Example using Gorilla Pat (https://github.com/gorilla/pat):
 
@@ -27,6 +32,7 @@ Example using Gorilla Pat (https://github.com/gorilla/pat):
 		router.Get("/thing", rest.GetAll(NewThingsRepository))
 		router.Post("/thing", rest.Post(NewThingsRepository))
 		router.Put("/thing/{id}", rest.Put(NewThingsRepository))
+		router.Add("PATCH", "/thing/{id}", rest.Patch(NewThingsRepository))
 		router.Delete("/thing/{id}", rest.Delete(NewThingsRepository))
 
 		http.Handle("/", router)
@@ -38,15 +44,17 @@ Example using Gorilla Pat (https://github.com/gorilla/pat):
 Example using chi router (https://github.com/go-chi/chi):
 
 	func main() {
+		rest.SetIDExtractor(rest.ChiID(chi.URLParam, "id"))
 		router := chi.NewRouter()
 
 		router.Route("/thing", func(r chi.Router) {
 			r.Get("/", rest.GetAll(NewThingsRepository))
 			r.Post("/", rest.Post(NewThingsRepository))
 			r.Route("/{id:[0-9]+}", func(r chi.Router) {
-				r.With(urlParams).Get("/", rest.Get(NewThingsRepository))
-				r.With(urlParams).Put("/", rest.Put(NewThingsRepository))
-				r.With(urlParams).Delete("/", rest.Delete(NewThingsRepository))
+				r.Get("/", rest.Get(NewThingsRepository))
+				r.Put("/", rest.Put(NewThingsRepository))
+				r.Patch("/", rest.Patch(NewThingsRepository))
+				r.Delete("/", rest.Delete(NewThingsRepository))
 			})
 		})
 
@@ -56,29 +64,6 @@ Example using chi router (https://github.com/go-chi/chi):
 		log.Fatal(http.ListenAndServe(":8000", nil))
 	}
 
-	// Middleware to convert Chi URL params (from Context) to query params, as expected by our REST package
-	func urlParams(next http.Handler) http.Handler {
-		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-			ctx := chi.RouteContext(r.Context())
-			parts := make([]string, 0)
-			for i, key := range ctx.URLParams.Keys {
-				value := ctx.URLParams.Values[i]
-				if key == "*" {
-					continue
-				}
-				parts = append(parts, url.QueryEscape(":"+key)+"="+url.QueryEscape(value))
-			}
-			q := strings.Join(parts, "&")
-			if r.URL.RawQuery == "" {
-				r.URL.RawQuery = q
-			} else {
-				r.URL.RawQuery += "&" + q
-			}
-
-			next.ServeHTTP(w, r)
-		})
-	}
-
 For more info see:
 	JSON Server: https://github.com/typicode/json-server
 	admin-on-rest: https://marmelab.com/admin-on-rest/
@@ -3,11 +3,17 @@ package rest
 import (
 	"errors"
 	"fmt"
+	"math"
+	"net/http"
+	"reflect"
+	"strconv"
+	"sync"
+	"time"
 )
 
 /*
-Possible errors returned by a Repository implementation. Any error other than these will make the REST controller
-return a 500 http status code.
+Possible errors returned by a Repository implementation. They can be wrapped (ex: with fmt.Errorf and %w). Any error
+other than these, and not handled by an ErrorMapper, will make the REST controller return a 500 http status code.
 */
 var (
 	// ErrNotFound will make the controller return a 404 error
@@ -15,8 +21,54 @@ var (
 
 	// ErrPermissionDenied will make the controller return a 403 error
 	ErrPermissionDenied = errors.New("permission denied")
+
+	// ErrConflict will make the controller return a 409 error. Ex: the entity was modified concurrently, or a unique
+	// constraint was violated
+	ErrConflict = errors.New("conflict")
+
+	// ErrUnauthorized will make the controller return a 401 error. Ex: the user is not authenticated
+	ErrUnauthorized = errors.New("unauthorized")
+
+	// ErrBadRequest will make the controller return a 400 error. Ex: invalid filter value. Wrap it to send the
+	// details to the client: fmt.Errorf("invalid age filter: %w", rest.ErrBadRequest)
+	ErrBadRequest = errors.New("bad request")
+
+	// ErrUnavailable will make the controller return a 503 error, with a Retry-After header. See UnavailableError
+	ErrUnavailable = errors.New("service unavailable")
 )
 
+// DefaultRetryAfter is the value of the Retry-After header sent with ErrUnavailable errors
+const DefaultRetryAfter = 30 * time.Second
+
+/*
+UnavailableError can be returned instead of ErrUnavailable to specify the value of the Retry-After header. It matches
+ErrUnavailable with errors.Is
+*/
+type UnavailableError struct {
+	// Time the client should wait before retrying. If zero, DefaultRetryAfter is used
+	RetryAfter time.Duration
+
+	// Cause of the error. Optional
+	Err error
+}
+
+func (e *UnavailableError) Error() string {
+	if e.Err == nil {
+		return ErrUnavailable.Error()
+	}
+	return fmt.Sprintf("%v: %v", ErrUnavailable, e.Err)
+}
+
+// Is reports whether target is ErrUnavailable
+func (e *UnavailableError) Is(target error) bool {
+	return target == ErrUnavailable
+}
+
+// Unwrap returns the cause of the error
+func (e *UnavailableError) Unwrap() error {
+	return e.Err
+}
+
 // ValidationError will make the controller return a 400 error, with the listed errors in the body
 type ValidationError struct {
 	Errors map[string]string `json:"errors"`
@@ -25,3 +77,193 @@ type ValidationError struct {
 func (m ValidationError) Error() string {
 	return fmt.Sprintf("Errors: %v", m.Errors)
 }
+
+/*
+ErrorResponse is the response sent to the client when a repository returns an error. See ErrorMapper
+*/
+type ErrorResponse struct {
+	// Http status code
+	Status int
+
+	// Message sent in the default error body. If empty, the error message is used (or "<entity> not found", for 404)
+	Message string
+
+	// Headers added to the response. Ex: Retry-After
+	Header http.Header
+
+	// Custom response body. If nil, the default error body is sent, with the Message. If it is a Problem, it is sent
+	// as application/problem+json
+	Body interface{}
+}
+
+/*
+ErrorMapper converts an error returned by a repository to the response sent to the client. It returns false if it
+does not handle the error. Mappers can be registered globally with RegisterErrorMapper, or per controller in
+Controller.ErrorMappers. Use MapError and MapErrorAs to create mappers for sentinel errors and error types.
+Errors not handled by any mapper return a 500 http status code
+*/
+type ErrorMapper func(err error) (ErrorResponse, bool)
+
+/*
+MapError returns an ErrorMapper for errors that match target, using errors.Is, so wrapped errors are also matched.
+Ex: rest.MapError(sql.ErrNoRows, http.StatusNotFound)
+*/
+func MapError(target error, status int, message ...string) ErrorMapper {
+	resp := ErrorResponse{Status: status}
+	if len(message) > 0 {
+		resp.Message = message[0]
+	}
+	return func(err error) (ErrorResponse, bool) {
+		return resp, errors.Is(err, target)
+	}
+}
+
+/*
+MapErrorAs returns an ErrorMapper for errors that can be assigned to the type pointed by target, using errors.As.
+target must be a non-nil pointer to a type implementing error, or to an interface type. If body is not nil, it is
+called with the matched error (with the same type as target's element) to build the response body. Ex:
+rest.MapErrorAs((*QuotaError)(nil), http.StatusTooManyRequests, nil)
+*/
+func MapErrorAs(target interface{}, status int, body func(err interface{}) interface{}) ErrorMapper {
+	typ := reflect.TypeOf(target)
+	if typ == nil || typ.Kind() != reflect.Ptr {
+		panic("rest: MapErrorAs target must be a pointer")
+	}
+	return func(err error) (ErrorResponse, bool) {
+		matched := reflect.New(typ)
+		if !errors.As(err, matched.Interface()) {
+			return ErrorResponse{}, false
+		}
+		resp := ErrorResponse{Status: status}
+		if body != nil {
+			resp.Body = body(matched.Elem().Interface())
+		}
+		return resp, true
+	}
+}
+
+var (
+	errorMappersMu sync.RWMutex
+	errorMappers   = []ErrorMapper{
+		mapValidationError,
+		mapUnavailable,
+		MapError(ErrBadRequest, http.StatusBadRequest),
+		MapError(ErrUnauthorized, http.StatusUnauthorized, "Unauthorized"),
+		MapError(ErrConflict, http.StatusConflict, "Conflict"),
+		MapError(ErrPermissionDenied, http.StatusForbidden, "Permission denied"),
+		MapError(ErrNotFound, http.StatusNotFound),
+	}
+)
+
+/*
+RegisterErrorMapper adds mappers to the global registry, used by all controllers. Mappers are tried in the reverse
+order they were registered, so applications can override the mappings of the standard errors
+*/
+func RegisterErrorMapper(mappers ...ErrorMapper) {
+	errorMappersMu.Lock()
+	defer errorMappersMu.Unlock()
+	errorMappers = append(errorMappers, mappers...)
+}
+
+// mapValidationError maps ValidationErrors, both by value and by reference, to a 400 response with the list of errors
+func mapValidationError(err error) (ErrorResponse, bool) {
+	var ptr *ValidationError
+	if errors.As(err, &ptr) && ptr != nil {
+		return ErrorResponse{Status: http.StatusBadRequest, Body: ptr}, true
+	}
+	var value ValidationError
+	if errors.As(err, &value) {
+		return ErrorResponse{Status: http.StatusBadRequest, Body: &value}, true
+	}
+	return ErrorResponse{}, false
+}
+
+// mapUnavailable maps ErrUnavailable to a 503 response, with the Retry-After header
+func mapUnavailable(err error) (ErrorResponse, bool) {
+	if !errors.Is(err, ErrUnavailable) {
+		return ErrorResponse{}, false
+	}
+	retryAfter := DefaultRetryAfter
+	var e *UnavailableError
+	if errors.As(err, &e) && e.RetryAfter > 0 {
+		retryAfter = e.RetryAfter
+	}
+	seconds := int64(math.Ceil(retryAfter.Seconds()))
+	return ErrorResponse{
+		Status:  http.StatusServiceUnavailable,
+		Message: "Service unavailable",
+		Header:  http.Header{"Retry-After": []string{strconv.FormatInt(seconds, 10)}},
+	}, true
+}
+
+// mapError returns the response for the error, trying the controller's mappers first, then the global ones
+func (c *Controller) mapError(err error) ErrorResponse {
+	for _, mapper := range c.ErrorMappers {
+		if resp, ok := mapper(err); ok {
+			return resp
+		}
+	}
+	errorMappersMu.RLock()
+	mappers := errorMappers
+	errorMappersMu.RUnlock()
+	for i := len(mappers) - 1; i >= 0; i-- {
+		if resp, ok := mappers[i](err); ok {
+			return resp
+		}
+	}
+	return ErrorResponse{Status: http.StatusInternalServerError}
+}
+
+/*
+respondWithRepositoryError maps the error returned by the repository to a response, logs it and sends it to the
+client. action and entity are used in the messages. Ex: "Reading", "thing(id:1)"
+*/
+func (c *Controller) respondWithRepositoryError(w http.ResponseWriter, r *http.Request, action string, entity string, err error) {
+	c.recordAccessError(r, err)
+	resp := c.mapError(err)
+	for k, v := range resp.Header {
+		w.Header()[k] = v
+	}
+	if resp.internal() {
+		c.respondWithInternalError(w, r, resp.Status, fmt.Sprintf("%s %s", action, entity), err)
+		return
+	}
+	c.logRepositoryError(r, resp, action, entity, err)
+	switch body := resp.Body.(type) {
+	case nil:
+		c.respondWithError(w, r, resp.Status, resp.message(action, entity, err))
+	case *ValidationError:
+		c.respondWithValidationError(w, r, body)
+	case Problem:
+		if body.Status == 0 {
+			body.Status = resp.Status
+		}
+		c.respondWithProblem(w, r, body)
+	default:
+		c.respond(w, r, resp.Status, body)
+	}
+}
+
+func (c *Controller) logRepositoryError(r *http.Request, resp ErrorResponse, action string, entity string, err error) {
+	if resp.Status >= http.StatusInternalServerError {
+		c.errorf(r, "%s %s: %v", action, entity, err)
+	} else {
+		c.warnf(r, "%s %s: %v", action, entity, err)
+	}
+}
+
+// internal returns true if the response is for an unexpected error, that needs to be sanitized
+func (resp ErrorResponse) internal() bool {
+	return resp.Status >= http.StatusInternalServerError && resp.Message == "" && resp.Body == nil
+}
+
+// message returns the message sent in the default error body
+func (resp ErrorResponse) message(action string, entity string, err error) string {
+	switch {
+	case resp.Message != "":
+		return fmt.Sprintf("%s %s: %s", action, entity, resp.Message)
+	case resp.Status == http.StatusNotFound:
+		return fmt.Sprintf("%s not found", entity)
+	}
+	return fmt.Sprintf("%s %s: %v", action, entity, err)
+}
@@ -0,0 +1,191 @@
+package rest_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/deluan/rest"
+	"github.com/deluan/rest/examples"
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+var errQuotaExceeded = errors.New("quota exceeded")
+
+type lockedError struct {
+	Owner string
+}
+
+func (e *lockedError) Error() string {
+	return "locked by " + e.Owner
+}
+
+func TestController_ErrorMapping(t *testing.T) {
+	defer rest.ErrorMappersSnapshot()()
+	rest.RegisterErrorMapper(
+		rest.MapError(errQuotaExceeded, http.StatusTooManyRequests),
+		rest.MapErrorAs((*lockedError)(nil), http.StatusLocked, func(err interface{}) interface{} {
+			return map[string]string{"owner": err.(*lockedError).Owner}
+		}),
+	)
+
+	Convey("Given a repository", t, func() {
+		handler, repo := createPersistableHandler(rest.Get)
+
+		Convey("When it returns a wrapped ErrNotFound", func() {
+			repo.Error = fmt.Errorf("finding sample: %w", rest.ErrNotFound)
+			req, res := createRequestResponse("GET", "/sample?:id=1", nil)
+			handler(res, req)
+
+			Convey("It returns 404 http status", func() {
+				So(res.Code, ShouldEqual, 404)
+				So(res.Body.String(), ShouldEqual, `{"error":"sample(id:1) not found"}`)
+			})
+		})
+
+		Convey("When it returns a wrapped ErrPermissionDenied", func() {
+			repo.Error = fmt.Errorf("checking user: %w", rest.ErrPermissionDenied)
+			req, res := createRequestResponse("GET", "/sample?:id=1", nil)
+			handler(res, req)
+
+			Convey("It returns 403 http status", func() {
+				So(res.Code, ShouldEqual, 403)
+				So(res.Body.String(), ShouldEqual, `{"error":"Reading sample(id:1): Permission denied"}`)
+			})
+		})
+
+		Convey("When it returns a ValidationError by value", func() {
+			postHandler := rest.Post(func(ctx context.Context) rest.Repository { return repo }, logger)
+			repo.Error = rest.ValidationError{Errors: map[string]string{"name": "required"}}
+			req, res := createRequestResponse("POST", "/sample", aRecordReader("0", "", 1))
+			postHandler(res, req)
+
+			Convey("It returns 400 http status and the list of errors", func() {
+				So(res.Code, ShouldEqual, 400)
+				So(res.Body.String(), ShouldEqual, `{"errors":{"name":"required"}}`)
+			})
+		})
+
+		Convey("When it returns a registered sentinel error", func() {
+			repo.Error = fmt.Errorf("reading: %w", errQuotaExceeded)
+			req, res := createRequestResponse("GET", "/sample?:id=1", nil)
+			handler(res, req)
+
+			Convey("It returns the mapped http status", func() {
+				So(res.Code, ShouldEqual, 429)
+				So(res.Body.String(), ShouldEqual, `{"error":"Reading sample(id:1): reading: quota exceeded"}`)
+			})
+		})
+
+		Convey("When it returns a registered error type", func() {
+			repo.Error = fmt.Errorf("reading: %w", &lockedError{Owner: "joe"})
+			req, res := createRequestResponse("GET", "/sample?:id=1", nil)
+			handler(res, req)
+
+			Convey("It returns the mapped http status and body", func() {
+				So(res.Code, ShouldEqual, 423)
+				So(res.Body.String(), ShouldEqual, `{"owner":"joe"}`)
+			})
+		})
+
+		Convey("When it returns an unknown error", func() {
+			repo.Error = errors.New("boom")
+			req, res := createRequestResponse("GET", "/sample?:id=1", nil)
+			handler(res, req)
+
+			Convey("It returns 500 http status", func() {
+				So(res.Code, ShouldEqual, 500)
+				So(res.Body.String(), ShouldEqual, `{"error":"boom"}`)
+			})
+		})
+	})
+
+	Convey("Given a controller with its own error mappers", t, func() {
+		repo := examples.NewPersistableSampleRepository(nil)
+		c := rest.Controller{Repository: repo, Logger: logger, ErrorMappers: []rest.ErrorMapper{
+			rest.MapError(rest.ErrNotFound, http.StatusGone, "Gone"),
+		}}
+
+		Convey("When the repository returns a mapped error", func() {
+			req, res := createRequestResponse("GET", "/sample?:id=1", nil)
+			c.Get(res, req)
+
+			Convey("It takes precedence over the global mappers", func() {
+				So(res.Code, ShouldEqual, 410)
+				So(res.Body.String(), ShouldEqual, `{"error":"Reading sample(id:1): Gone"}`)
+			})
+		})
+
+		Convey("When I call BulkDelete with a missing id", func() {
+			req, res := createRequestResponse("DELETE", "/sample?id=1", nil)
+			c.BulkDelete(res, req)
+
+			Convey("It uses the mappers in the results", func() {
+				var results []rest.BulkResult
+				_ = json.Unmarshal(res.Body.Bytes(), &results)
+				So(results, ShouldResemble, []rest.BulkResult{{ID: "1", Status: 410, Error: "Deleting sample(id:1): Gone"}})
+			})
+		})
+	})
+}
+
+func TestController_StandardErrors(t *testing.T) {
+	methods := []struct {
+		method  string
+		target  string
+		wrapper handlerWrapper
+	}{
+		{"GET", "/sample?:id=1", rest.Get},
+		{"GET", "/sample", rest.GetAll},
+		{"POST", "/sample", rest.Post},
+		{"PUT", "/sample?:id=1", rest.Put},
+		{"DELETE", "/sample?:id=1", rest.Delete},
+	}
+	call := func(wrapper handlerWrapper, method, target string, err error) *httptest.ResponseRecorder {
+		handler, repo := createPersistableHandler(wrapper)
+		repo.Error = err
+		req, res := createRequestResponse(method, target, aRecordReader("1", "John", 30))
+		handler(res, req)
+		return res
+	}
+
+	Convey("Given a repository returning the standard errors", t, func() {
+		for _, m := range methods {
+			Convey("When I call "+m.method+" "+m.target, func() {
+				Convey("ErrUnauthorized returns 401 http status", func() {
+					res := call(m.wrapper, m.method, m.target, rest.ErrUnauthorized)
+					So(res.Code, ShouldEqual, 401)
+				})
+
+				Convey("ErrBadRequest returns 400 http status, with the details", func() {
+					res := call(m.wrapper, m.method, m.target, fmt.Errorf("invalid age filter: %w", rest.ErrBadRequest))
+					So(res.Code, ShouldEqual, 400)
+					So(res.Body.String(), ShouldContainSubstring, "invalid age filter")
+				})
+
+				Convey("ErrConflict returns 409 http status", func() {
+					res := call(m.wrapper, m.method, m.target, rest.ErrConflict)
+					So(res.Code, ShouldEqual, 409)
+				})
+
+				Convey("ErrUnavailable returns 503 http status and the default Retry-After", func() {
+					res := call(m.wrapper, m.method, m.target, rest.ErrUnavailable)
+					So(res.Code, ShouldEqual, 503)
+					So(res.Header().Get("Retry-After"), ShouldEqual, "30")
+				})
+
+				Convey("UnavailableError returns 503 http status and its Retry-After", func() {
+					err := &rest.UnavailableError{RetryAfter: 90 * time.Second, Err: errors.New("db down")}
+					res := call(m.wrapper, m.method, m.target, fmt.Errorf("connecting: %w", err))
+					So(res.Code, ShouldEqual, 503)
+					So(res.Header().Get("Retry-After"), ShouldEqual, "90")
+				})
+			})
+		}
+	})
+}
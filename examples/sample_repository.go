@@ -3,7 +3,11 @@ package examples
 import (
 	"context"
 	"errors"
+	"fmt"
+	"reflect"
+	"sort"
 	"strconv"
+	"strings"
 
 	"github.com/deluan/rest"
 )
@@ -30,7 +34,8 @@ type SampleModel struct {
 	Age  int
 }
 
-// SampleRepository is a simple in-memory repository implementation. NOTE: This repository does not handle QueryOptions
+// SampleRepository is a simple in-memory repository implementation. NOTE: This repository only handles the Search,
+// SortFields and Max (in cursor mode) fields of QueryOptions
 type SampleRepository struct {
 	Context context.Context
 	Error   error
@@ -39,7 +44,7 @@ type SampleRepository struct {
 }
 
 func (r *SampleRepository) Count(options ...rest.QueryOptions) (int64, error) {
-	return int64(len(r.data)), r.Error
+	return int64(len(r.find(options...))), r.Error
 }
 
 func (r *SampleRepository) Read(id string) (interface{}, error) {
@@ -56,11 +61,127 @@ func (r *SampleRepository) ReadAll(options ...rest.QueryOptions) (interface{}, e
 	if r.Error != nil {
 		return nil, r.Error
 	}
+	return r.find(options...), nil
+}
+
+func (r *SampleRepository) find(options ...rest.QueryOptions) []SampleModel {
+	var opts rest.QueryOptions
+	if len(options) > 0 {
+		opts = options[0]
+	}
 	dataSet := make([]SampleModel, 0)
 	for _, v := range r.data {
-		dataSet = append(dataSet, v)
+		if matchesSearch(v, opts.Search) {
+			dataSet = append(dataSet, v)
+		}
+	}
+	sort.SliceStable(dataSet, func(i, j int) bool {
+		return compare(dataSet[i], entityKey(dataSet[j]), opts.SortFields) < 0
+	})
+	return dataSet
+}
+
+// matchesSearch returns true if any string field of the entity contains the term (case insensitive)
+func matchesSearch(entity interface{}, term string) bool {
+	if term == "" {
+		return true
+	}
+	term = strings.ToLower(term)
+	v := reflect.Indirect(reflect.ValueOf(entity))
+	for i := 0; i < v.NumField(); i++ {
+		f := v.Field(i)
+		if f.Kind() == reflect.String && strings.Contains(strings.ToLower(f.String()), term) {
+			return true
+		}
+	}
+	return false
+}
+
+// ReadAllAfter implements rest.CursorPaginated, returning the page of records that come after the cursor
+func (r *SampleRepository) ReadAllAfter(cursor rest.Cursor, options rest.QueryOptions) (interface{}, rest.Cursor, error) {
+	if r.Error != nil {
+		return nil, nil, r.Error
+	}
+	dataSet := r.find(options)
+	if cursor != nil {
+		start := sort.Search(len(dataSet), func(i int) bool {
+			return compare(dataSet[i], cursorKey(cursor), options.SortFields) > 0
+		})
+		dataSet = dataSet[start:]
+	}
+	if options.Max == 0 || len(dataSet) <= options.Max {
+		return dataSet, nil, nil
+	}
+	dataSet = dataSet[:options.Max]
+	next := rest.Cursor{}
+	for _, sf := range options.SortFields {
+		if f := fieldByName(dataSet[len(dataSet)-1], sf.Field); f.IsValid() {
+			next[sf.Field] = f.Interface()
+		}
+	}
+	return dataSet, next, nil
+}
+
+type keyFunc func(field string) (interface{}, bool)
+
+func entityKey(entity interface{}) keyFunc {
+	return func(field string) (interface{}, bool) {
+		f := fieldByName(entity, field)
+		if !f.IsValid() {
+			return nil, false
+		}
+		return f.Interface(), true
+	}
+}
+
+func cursorKey(cursor rest.Cursor) keyFunc {
+	return func(field string) (interface{}, bool) {
+		v, ok := cursor[field]
+		return v, ok
 	}
-	return dataSet, nil
+}
+
+// compare returns a negative number if the entity comes before the key in the order specified by fields, a positive
+// number if it comes after, and 0 if they are equal
+func compare(entity interface{}, key keyFunc, fields []rest.SortField) int {
+	for _, sf := range fields {
+		f := fieldByName(entity, sf.Field)
+		k, ok := key(sf.Field)
+		if !f.IsValid() || !ok {
+			continue
+		}
+		var cmp int
+		switch f.Kind() {
+		case reflect.String:
+			cmp = strings.Compare(f.String(), fmt.Sprint(k))
+		case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+			n, _ := strconv.ParseInt(fmt.Sprint(k), 10, 64)
+			cmp = compareInt(f.Int(), n)
+		}
+		if cmp != 0 {
+			if sf.Desc {
+				return -cmp
+			}
+			return cmp
+		}
+	}
+	return 0
+}
+
+// fieldByName returns the field of the entity with the given name (case insensitive)
+func fieldByName(entity interface{}, name string) reflect.Value {
+	v := reflect.Indirect(reflect.ValueOf(entity))
+	return v.FieldByNameFunc(func(n string) bool { return strings.EqualFold(n, name) })
+}
+
+func compareInt(a, b int64) int {
+	switch {
+	case a < b:
+		return -1
+	case a > b:
+		return 1
+	}
+	return 0
 }
 
 func (r *SampleRepository) EntityName() string {
@@ -80,6 +201,9 @@ func NewPersistableSampleRepository(ctx context.Context, logger ...rest.Logger)
 
 type PersistableSampleRepository struct {
 	SampleRepository
+
+	// Fields received in the last call to Update
+	UpdatedCols []string
 }
 
 func (r *PersistableSampleRepository) Save(entity interface{}) (string, error) {
@@ -107,6 +231,7 @@ func (r *PersistableSampleRepository) Update(id string, entity interface{}, cols
 	}
 
 	r.data[rec.ID] = *rec
+	r.UpdatedCols = cols
 	return nil
 }
 
@@ -0,0 +1,31 @@
+package rest
+
+// Exported for the tests in the rest_test package, so they can restore the registries they change
+var ErrorMappersSnapshot = errorMappersSnapshot
+
+// Exported for the tests in the rest_test package, to calculate the expected ETags of Versioned entities
+var VersionTag = versionTag
+
+// errorMappersSnapshot returns a function that restores the error mappers registry to its current state
+func errorMappersSnapshot() (restore func()) {
+	errorMappersMu.RLock()
+	saved := append([]ErrorMapper(nil), errorMappers...)
+	errorMappersMu.RUnlock()
+	return func() {
+		errorMappersMu.Lock()
+		defer errorMappersMu.Unlock()
+		errorMappers = saved
+	}
+}
+
+// renderersSnapshot returns a function that restores the renderers registry to its current state
+func renderersSnapshot() (restore func()) {
+	renderersMu.RLock()
+	saved := append([]registeredRenderer(nil), renderers...)
+	renderersMu.RUnlock()
+	return func() {
+		renderersMu.Lock()
+		defer renderersMu.Unlock()
+		renderers = saved
+	}
+}
@@ -10,10 +10,7 @@ Get handles the GET verb for individual items. Should be mapped to:
 GET /thing/:id
 */
 func Get(newRepository RepositoryConstructor, logger ...Logger) http.HandlerFunc {
-	return func(w http.ResponseWriter, r *http.Request) {
-		c := createController(newRepository, r.Context(), logger...)
-		c.Get(w, r)
-	}
+	return Configure(loggerOptions(logger)...).Get(newRepository)
 }
 
 /*
@@ -22,10 +19,7 @@ GET /thing
 For all query options available, see https://github.com/typicode/json-server
 */
 func GetAll(newRepository RepositoryConstructor, logger ...Logger) http.HandlerFunc {
-	return func(w http.ResponseWriter, r *http.Request) {
-		c := createController(newRepository, r.Context(), logger...)
-		c.GetAll(w, r)
-	}
+	return Configure(loggerOptions(logger)...).GetAll(newRepository)
 }
 
 /*
@@ -33,10 +27,7 @@ Post handles the POST verb. Should be mapped to:
 POST /thing
 */
 func Post(newRepository RepositoryConstructor, logger ...Logger) http.HandlerFunc {
-	return func(w http.ResponseWriter, r *http.Request) {
-		c := createController(newRepository, r.Context(), logger...)
-		c.Post(w, r)
-	}
+	return Configure(loggerOptions(logger)...).Post(newRepository)
 }
 
 /*
@@ -44,10 +35,16 @@ Put handles the PUT verb. Should be mapped to:
 PUT /thing/:id
 */
 func Put(newRepository RepositoryConstructor, logger ...Logger) http.HandlerFunc {
-	return func(w http.ResponseWriter, r *http.Request) {
-		c := createController(newRepository, r.Context(), logger...)
-		c.Put(w, r)
-	}
+	return Configure(loggerOptions(logger)...).Put(newRepository)
+}
+
+/*
+Patch handles the PATCH verb, for partial updates using JSON Merge Patch (RFC 7396, the default) or JSON Patch
+(RFC 6902, when the Content-Type is application/json-patch+json). Should be mapped to:
+PATCH /thing/:id
+*/
+func Patch(newRepository RepositoryConstructor, logger ...Logger) http.HandlerFunc {
+	return Configure(loggerOptions(logger)...).Patch(newRepository)
 }
 
 /*
@@ -55,16 +52,103 @@ Delete handles the DELETE verb. Should be mapped to:
 DELETE /thing/:id
 */
 func Delete(newRepository RepositoryConstructor, logger ...Logger) http.HandlerFunc {
+	return Configure(loggerOptions(logger)...).Delete(newRepository)
+}
+
+/*
+BulkPost handles the POST verb for a list of entities, sent as a JSON array. Should be mapped to a collection route,
+ex:
+POST /thing/bulk
+Responds with a list of BulkResults, one for each entity received, in the same order
+*/
+func BulkPost(newRepository RepositoryConstructor, logger ...Logger) http.HandlerFunc {
+	return Configure(loggerOptions(logger)...).BulkPost(newRepository)
+}
+
+/*
+BulkDelete handles the DELETE verb for a list of entities, identified by repeated id params. Should be mapped to:
+DELETE /thing?id=1&id=2
+Responds with a list of BulkResults, one for each id received, in the same order
+*/
+func BulkDelete(newRepository RepositoryConstructor, logger ...Logger) http.HandlerFunc {
+	return Configure(loggerOptions(logger)...).BulkDelete(newRepository)
+}
+
+/*
+Handlers creates handlers configured with a set of Options. Its methods are the same as the handler functions of
+this package (Get, GetAll...), but use the Options instead of a Logger. Ex:
+
+	handlers := rest.Configure(rest.WithLogger(logger), rest.WithMaxPageSize(100))
+	router.Get("/thing", handlers.GetAll(NewThingsRepository))
+*/
+type Handlers struct {
+	options []Option
+}
+
+// Configure returns Handlers that create controllers configured with the options
+func Configure(options ...Option) Handlers {
+	return Handlers{options: options}
+}
+
+// Get handles the GET verb for individual items. See the Get function
+func (h Handlers) Get(newRepository RepositoryConstructor) http.HandlerFunc {
+	return h.handle(newRepository, (*Controller).Get)
+}
+
+// GetAll handles the GET verb for the full collection. See the GetAll function
+func (h Handlers) GetAll(newRepository RepositoryConstructor) http.HandlerFunc {
+	return h.handle(newRepository, (*Controller).GetAll)
+}
+
+// Post handles the POST verb. See the Post function
+func (h Handlers) Post(newRepository RepositoryConstructor) http.HandlerFunc {
+	return h.handle(newRepository, (*Controller).Post)
+}
+
+// Put handles the PUT verb. See the Put function
+func (h Handlers) Put(newRepository RepositoryConstructor) http.HandlerFunc {
+	return h.handle(newRepository, (*Controller).Put)
+}
+
+// Patch handles the PATCH verb. See the Patch function
+func (h Handlers) Patch(newRepository RepositoryConstructor) http.HandlerFunc {
+	return h.handle(newRepository, (*Controller).Patch)
+}
+
+// Delete handles the DELETE verb. See the Delete function
+func (h Handlers) Delete(newRepository RepositoryConstructor) http.HandlerFunc {
+	return h.handle(newRepository, (*Controller).Delete)
+}
+
+// BulkPost handles the POST verb for a list of entities. See the BulkPost function
+func (h Handlers) BulkPost(newRepository RepositoryConstructor) http.HandlerFunc {
+	return h.handle(newRepository, (*Controller).BulkPost)
+}
+
+// BulkDelete handles the DELETE verb for a list of entities. See the BulkDelete function
+func (h Handlers) BulkDelete(newRepository RepositoryConstructor) http.HandlerFunc {
+	return h.handle(newRepository, (*Controller).BulkDelete)
+}
+
+func (h Handlers) handle(newRepository RepositoryConstructor,
+	method func(*Controller, http.ResponseWriter, *http.Request)) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
-		c := createController(newRepository, r.Context(), logger...)
-		c.Delete(w, r)
+		c := createController(newRepository, r.Context(), h.options)
+		method(c, w, r)
+	}
+}
+
+func loggerOptions(logger []Logger) []Option {
+	if len(logger) == 0 {
+		return nil
 	}
+	return []Option{WithLogger(logger[0])}
 }
 
-func createController(newRepository RepositoryConstructor, ctx context.Context, logger ...Logger) Controller {
-	c := Controller{Repository: newRepository(ctx)}
-	if len(logger) > 0 {
-		c.Logger = logger[0]
+func createController(newRepository RepositoryConstructor, ctx context.Context, options []Option) *Controller {
+	c := NewController(newRepository(ctx), options...)
+	if entry, ok := ctx.Value(accessEntryKey{}).(*accessEntry); ok {
+		entry.entity = c.repository().EntityName()
 	}
 	return c
 }
@@ -0,0 +1,71 @@
+package rest
+
+import (
+	"net/http"
+	"sync"
+)
+
+/*
+IDExtractor returns the id of the entity from the request, for the Get, Put, Patch and Delete handlers. The default
+extractor, QueryID(":id"), reads it from the ":id" query param, as set by Gorilla Pat. See also ChiID, MuxID and
+PathValueID (Go 1.22+)
+*/
+type IDExtractor func(r *http.Request) string
+
+// QueryID returns an IDExtractor that reads the id from the query param with the specified name
+func QueryID(name string) IDExtractor {
+	return func(r *http.Request) string {
+		return r.URL.Query().Get(name)
+	}
+}
+
+/*
+ChiID returns an IDExtractor that reads the id from the chi route context. As this package does not depend on chi,
+pass chi's URLParam function. Ex:
+rest.ChiID(chi.URLParam, "id")
+*/
+func ChiID(urlParam func(r *http.Request, key string) string, name string) IDExtractor {
+	return func(r *http.Request) string {
+		return urlParam(r, name)
+	}
+}
+
+/*
+MuxID returns an IDExtractor that reads the id from the gorilla/mux route variables. As this package does not depend
+on gorilla/mux, pass its Vars function. Ex:
+rest.MuxID(mux.Vars, "id")
+*/
+func MuxID(vars func(r *http.Request) map[string]string, name string) IDExtractor {
+	return func(r *http.Request) string {
+		return vars(r)[name]
+	}
+}
+
+var (
+	idExtractorMu sync.RWMutex
+	idExtractor   = QueryID(":id")
+)
+
+/*
+SetIDExtractor sets the default IDExtractor for the controllers created by the handler functions. Ex:
+rest.SetIDExtractor(rest.PathValueID("id"))
+*/
+func SetIDExtractor(extractor IDExtractor) {
+	idExtractorMu.Lock()
+	defer idExtractorMu.Unlock()
+	idExtractor = extractor
+}
+
+func defaultIDExtractor() IDExtractor {
+	idExtractorMu.RLock()
+	defer idExtractorMu.RUnlock()
+	return idExtractor
+}
+
+// id returns the id of the entity, extracted from the request by the controller's IDExtractor
+func (c *Controller) id(r *http.Request) string {
+	if c.IDExtractor == nil {
+		return r.URL.Query().Get(":id")
+	}
+	return c.IDExtractor(r)
+}
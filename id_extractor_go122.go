@@ -0,0 +1,16 @@
+//go:build go1.22
+// +build go1.22
+
+package rest
+
+import "net/http"
+
+/*
+PathValueID returns an IDExtractor that reads the id from the path wildcards matched by the standard http.ServeMux.
+Ex: for the pattern "GET /thing/{id}", use rest.PathValueID("id")
+*/
+func PathValueID(name string) IDExtractor {
+	return func(r *http.Request) string {
+		return r.PathValue(name)
+	}
+}
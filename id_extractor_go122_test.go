@@ -0,0 +1,33 @@
+//go:build go1.22
+// +build go1.22
+
+package rest_test
+
+import (
+	"testing"
+
+	"github.com/deluan/rest"
+	"github.com/deluan/rest/examples"
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestController_PathValueID(t *testing.T) {
+	Convey("Given a Controller with the PathValueID extractor", t, func() {
+		repo := examples.NewPersistableSampleRepository(nil)
+		joe := aRecord("Joe", 30)
+		id, _ := repo.Save(&joe)
+		c := rest.Controller{Repository: repo, Logger: logger, IDExtractor: rest.PathValueID("id")}
+
+		Convey("When I call Get", func() {
+			req, res := createRequestResponse("GET", "/sample/"+id, nil)
+			// Set by http.ServeMux, for a "GET /sample/{id}" pattern
+			req.SetPathValue("id", id)
+			c.Get(res, req)
+
+			Convey("It reads the id from the path", func() {
+				So(res.Code, ShouldEqual, 200)
+				So(res.Body.String(), ShouldContainSubstring, `"Name":"Joe"`)
+			})
+		})
+	})
+}
@@ -0,0 +1,82 @@
+package rest_test
+
+import (
+	"context"
+	"net/http"
+	"testing"
+
+	"github.com/deluan/rest"
+	"github.com/deluan/rest/examples"
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+type routeParamsKey struct{}
+
+// routeParams simulates a router that keeps the route params in the request context
+func routeParams(r *http.Request, params map[string]string) *http.Request {
+	return r.WithContext(context.WithValue(r.Context(), routeParamsKey{}, params))
+}
+
+func urlParam(r *http.Request, key string) string {
+	params, _ := r.Context().Value(routeParamsKey{}).(map[string]string)
+	return params[key]
+}
+
+func vars(r *http.Request) map[string]string {
+	params, _ := r.Context().Value(routeParamsKey{}).(map[string]string)
+	return params
+}
+
+func TestController_IDExtractor(t *testing.T) {
+	Convey("Given a repository with one item", t, func() {
+		repo := examples.NewPersistableSampleRepository(nil)
+		joe := aRecord("Joe", 30)
+		id, _ := repo.Save(&joe)
+
+		Convey("When I call Get with the default IDExtractor", func() {
+			c := rest.Controller{Repository: repo, Logger: logger}
+			req, res := createRequestResponse("GET", "/sample?:id="+id, nil)
+			c.Get(res, req)
+
+			Convey("It reads the id from the :id query param", func() {
+				So(res.Code, ShouldEqual, 200)
+			})
+		})
+
+		Convey("When I call Get with a chi IDExtractor", func() {
+			c := rest.Controller{Repository: repo, Logger: logger, IDExtractor: rest.ChiID(urlParam, "id")}
+			req, res := createRequestResponse("GET", "/sample/"+id, nil)
+			c.Get(res, routeParams(req, map[string]string{"id": id}))
+
+			Convey("It reads the id from the route params", func() {
+				So(res.Code, ShouldEqual, 200)
+			})
+		})
+
+		Convey("When I call Delete with a gorilla/mux IDExtractor", func() {
+			c := rest.Controller{Repository: repo, Logger: logger, IDExtractor: rest.MuxID(vars, "thingID")}
+			req, res := createRequestResponse("DELETE", "/sample/"+id, nil)
+			c.Delete(res, routeParams(req, map[string]string{"thingID": id}))
+
+			Convey("It reads the id from the route vars", func() {
+				So(res.Code, ShouldEqual, 200)
+				count, _ := repo.Count()
+				So(count, ShouldEqual, 0)
+			})
+		})
+
+		Convey("When I set the default IDExtractor for the handlers", func() {
+			rest.SetIDExtractor(rest.QueryID("id"))
+			defer rest.SetIDExtractor(rest.QueryID(":id"))
+			handler, repo := createPersistableHandler(rest.Get)
+			joe := aRecord("Joe", 30)
+			id, _ := repo.Save(&joe)
+			req, res := createRequestResponse("GET", "/sample?id="+id, nil)
+			handler(res, req)
+
+			Convey("The handlers use it", func() {
+				So(res.Code, ShouldEqual, 200)
+			})
+		})
+	})
+}
@@ -0,0 +1,144 @@
+package rest
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+/*
+paginationLinks returns the value for a RFC 5988 Link header, with the first, prev, next and last pages of the
+collection. The links follow the same pagination style used in the request (_page/_limit or _start/_end/_limit),
+keeping all other query params untouched. In cursor mode (when the entities were read with ReadAllAfter), only the
+first and next links are returned. If the request is not paginated, it returns an empty string.
+*/
+func paginationLinks(r *http.Request, options QueryOptions, count int64, next string, cursorMode bool) string {
+	u := requestURL(r)
+	params := u.Query()
+	if !cursorMode && options.Max <= 0 {
+		return ""
+	}
+
+	var links []string
+	add := func(rel string, values map[string]string) {
+		link := *u
+		link.RawQuery = replaceParams(u.RawQuery, values)
+		links = append(links, fmt.Sprintf(`<%s>; rel="%s"`, link.String(), rel))
+	}
+
+	if cursorMode {
+		add("first", map[string]string{"_cursor": ""})
+		if next != "" {
+			add("next", map[string]string{"_cursor": next})
+		}
+		return strings.Join(links, ", ")
+	}
+
+	max := int64(options.Max)
+	offset := int64(options.Offset)
+	last := int64(0)
+	if count > 0 {
+		last = (count - 1) / max * max
+	}
+	hasStartEnd := params.Get("_start") != "" || params.Get("_end") != ""
+
+	// Page style links
+	if params.Get("_page") != "" && !hasStartEnd {
+		page := offset/max + 1
+		pageLink := func(rel string, page int64) {
+			add(rel, map[string]string{"_page": strconv.FormatInt(page, 10)})
+		}
+		pageLink("first", 1)
+		if page > 1 {
+			pageLink("prev", page-1)
+		}
+		if offset+max < count {
+			pageLink("next", page+1)
+		}
+		pageLink("last", last/max+1)
+		return strings.Join(links, ", ")
+	}
+
+	// Offset style links
+	useEnd := params.Get("_end") != ""
+	startLink := func(rel string, start, size int64) {
+		values := map[string]string{"_start": strconv.FormatInt(start, 10)}
+		if useEnd {
+			values["_end"] = strconv.FormatInt(start+size, 10)
+		} else if size != max {
+			values["_limit"] = strconv.FormatInt(size, 10)
+		}
+		add(rel, values)
+	}
+	startLink("first", 0, max)
+	if offset > 0 {
+		// The prev page ends at the current offset, so it does not overlap the current page when the offset is not
+		// a multiple of the page size
+		prev := offset - max
+		if prev < 0 {
+			prev = 0
+		}
+		startLink("prev", prev, offset-prev)
+	}
+	if offset+max < count {
+		startLink("next", offset+max, max)
+	}
+	startLink("last", last, max)
+	return strings.Join(links, ", ")
+}
+
+// requestURL returns the URL as sent by the client, before any changes made by routers or middlewares
+func requestURL(r *http.Request) *url.URL {
+	if r.RequestURI != "" {
+		if u, err := url.ParseRequestURI(r.RequestURI); err == nil {
+			return u
+		}
+	}
+	u := *r.URL
+	return &u
+}
+
+// replaceParams sets the values of the params in the raw query, keeping the order and encoding of all other params.
+// Params not present in the raw query are appended to the end
+func replaceParams(rawQuery string, values map[string]string) string {
+	var parts []string
+	replaced := map[string]bool{}
+	for _, part := range strings.Split(rawQuery, "&") {
+		if part == "" {
+			continue
+		}
+		key := part
+		if i := strings.Index(part, "="); i >= 0 {
+			key = part[:i]
+		}
+		if k, err := url.QueryUnescape(key); err == nil {
+			key = k
+		}
+		if v, ok := values[key]; ok {
+			if !replaced[key] {
+				parts = append(parts, url.QueryEscape(key)+"="+url.QueryEscape(v))
+				replaced[key] = true
+			}
+			continue
+		}
+		parts = append(parts, part)
+	}
+	for _, key := range sortedKeys(values) {
+		if !replaced[key] {
+			parts = append(parts, url.QueryEscape(key)+"="+url.QueryEscape(values[key]))
+		}
+	}
+	return strings.Join(parts, "&")
+}
+
+func sortedKeys(m map[string]string) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
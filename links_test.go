@@ -0,0 +1,103 @@
+package rest
+
+import (
+	"net/http/httptest"
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func Test_paginationLinks(t *testing.T) {
+	c := &Controller{Logger: logger}
+	paginate := func(target string, count int64, next string, cursorMode bool) string {
+		r := httptest.NewRequest("GET", target, nil)
+		options := c.parseOptions(r, r.URL.Query())
+		return paginationLinks(r, options, count, next, cursorMode)
+	}
+	links := func(target string, count int64, next string) string {
+		return paginate(target, count, next, false)
+	}
+	cursorLinks := func(target string, count int64, next string) string {
+		return paginate(target, count, next, true)
+	}
+
+	Convey("Given a request without pagination", t, func() {
+		Convey("It returns no links", func() {
+			So(links("/thing?name=joe", 100, ""), ShouldBeEmpty)
+		})
+	})
+
+	Convey("Given a request with _start and _end", t, func() {
+		Convey("It returns links keeping the other params", func() {
+			So(links("/thing?name=joe&_start=20&_end=30&_sort=name", 45, ""), ShouldEqual,
+				`</thing?name=joe&_start=0&_end=10&_sort=name>; rel="first", `+
+					`</thing?name=joe&_start=10&_end=20&_sort=name>; rel="prev", `+
+					`</thing?name=joe&_start=30&_end=40&_sort=name>; rel="next", `+
+					`</thing?name=joe&_start=40&_end=50&_sort=name>; rel="last"`)
+		})
+
+		Convey("It does not return prev in the first page, nor next in the last page", func() {
+			So(links("/thing?_start=0&_end=10", 10, ""), ShouldEqual,
+				`</thing?_start=0&_end=10>; rel="first", `+
+					`</thing?_start=0&_end=10>; rel="last"`)
+		})
+
+		Convey("It ends the prev link at the current offset, when it is not a multiple of the page size", func() {
+			So(links("/thing?_start=5&_end=15", 30, ""), ShouldEqual,
+				`</thing?_start=0&_end=10>; rel="first", `+
+					`</thing?_start=0&_end=5>; rel="prev", `+
+					`</thing?_start=15&_end=25>; rel="next", `+
+					`</thing?_start=20&_end=30>; rel="last"`)
+		})
+	})
+
+	Convey("Given a request with an unaligned _start and _limit", t, func() {
+		Convey("It reduces the _limit of the prev link", func() {
+			So(links("/thing?_start=5&_limit=10", 30, ""), ShouldEqual,
+				`</thing?_start=0&_limit=10>; rel="first", `+
+					`</thing?_start=0&_limit=5>; rel="prev", `+
+					`</thing?_start=15&_limit=10>; rel="next", `+
+					`</thing?_start=20&_limit=10>; rel="last"`)
+		})
+	})
+
+	Convey("Given a request with only _limit", t, func() {
+		Convey("It adds the _start param", func() {
+			So(links("/thing?_limit=10", 25, ""), ShouldEqual,
+				`</thing?_limit=10&_start=0>; rel="first", `+
+					`</thing?_limit=10&_start=10>; rel="next", `+
+					`</thing?_limit=10&_start=20>; rel="last"`)
+		})
+	})
+
+	Convey("Given a request with _page and _limit", t, func() {
+		Convey("It returns page links", func() {
+			So(links("/thing?_page=2&_limit=10&q=a%20b", 35, ""), ShouldEqual,
+				`</thing?_page=1&_limit=10&q=a%20b>; rel="first", `+
+					`</thing?_page=1&_limit=10&q=a%20b>; rel="prev", `+
+					`</thing?_page=3&_limit=10&q=a%20b>; rel="next", `+
+					`</thing?_page=4&_limit=10&q=a%20b>; rel="last"`)
+		})
+	})
+
+	Convey("Given a request in cursor mode", t, func() {
+		Convey("It returns the first and next links", func() {
+			So(cursorLinks("/thing?_cursor=abc&_limit=10", 35, "def"), ShouldEqual,
+				`</thing?_cursor=&_limit=10>; rel="first", `+
+					`</thing?_cursor=def&_limit=10>; rel="next"`)
+		})
+
+		Convey("It does not return the next link in the last page", func() {
+			So(cursorLinks("/thing?_cursor=abc&_limit=10", 35, ""), ShouldEqual, `</thing?_cursor=&_limit=10>; rel="first"`)
+		})
+	})
+
+	Convey("Given a request with a _cursor param, read with offset pagination", t, func() {
+		Convey("It returns offset links", func() {
+			So(links("/thing?_cursor=&_limit=10", 35, ""), ShouldEqual,
+				`</thing?_cursor=&_limit=10&_start=0>; rel="first", `+
+					`</thing?_cursor=&_limit=10&_start=10>; rel="next", `+
+					`</thing?_cursor=&_limit=10&_start=30>; rel="last"`)
+		})
+	})
+}
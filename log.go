@@ -1,5 +1,12 @@
 package rest
 
+import (
+	"context"
+	"fmt"
+	"log"
+	"net/http"
+)
+
 /*
 A Logger instance can be passed to the handlers provided by this package. This is compatible with Logrus, but also
 allows for full customization of the log system used. If you want to use a different logger, just implement a wrapper
@@ -9,3 +16,86 @@ type Logger interface {
 	Warnf(format string, args ...interface{})
 	Errorf(format string, args ...interface{})
 }
+
+// Level is the severity of a log entry
+type Level int
+
+// Log levels used by the Controller
+const (
+	LevelDebug Level = iota
+	LevelInfo
+	LevelWarn
+	LevelError
+)
+
+func (l Level) String() string {
+	switch l {
+	case LevelDebug:
+		return "debug"
+	case LevelInfo:
+		return "info"
+	case LevelWarn:
+		return "warn"
+	case LevelError:
+		return "error"
+	}
+	return fmt.Sprintf("level(%d)", int(l))
+}
+
+// Fields are the structured data added to a log entry. Ex: {"entity": "thing", "id": "1"}
+type Fields map[string]interface{}
+
+/*
+StructuredLogger is a Logger with levels and structured fields. If the Logger passed to the handlers also implements
+this interface, the Controller uses it instead of Warnf and Errorf, logging debug traces and adding request-scoped
+fields to all entries: method, path, entity, id (when available) and request_id (from the X-Request-ID header).
+See NewSlogLogger for Go 1.21+, and the logrusadapter package
+*/
+type StructuredLogger interface {
+	Logger
+
+	// Writes a log entry. ctx is the context of the current request
+	Log(ctx context.Context, level Level, msg string, fields Fields)
+}
+
+// logf writes a log entry with the request-scoped fields (if r is not nil), if the Logger is a StructuredLogger.
+// Otherwise, debug and info messages are discarded
+func (c *Controller) logf(r *http.Request, level Level, format string, args ...interface{}) {
+	if sl, ok := c.Logger.(StructuredLogger); ok {
+		ctx := context.Background()
+		if r != nil {
+			ctx = r.Context()
+		}
+		sl.Log(ctx, level, fmt.Sprintf(format, args...), c.requestFields(r))
+		return
+	}
+	switch {
+	case level < LevelWarn:
+		return
+	case c.Logger == nil:
+		log.Printf(format, args...)
+	case level == LevelWarn:
+		c.Logger.Warnf(format, args...)
+	default:
+		c.Logger.Errorf(format, args...)
+	}
+}
+
+func (c *Controller) requestFields(r *http.Request) Fields {
+	fields := Fields{}
+	if c.repository() != nil {
+		fields["entity"] = c.repository().EntityName()
+	}
+	if r == nil {
+		return fields
+	}
+	fields["method"] = r.Method
+	fields["path"] = r.URL.Path
+	if id := c.id(r); id != "" {
+		fields["id"] = id
+	}
+	if requestID := r.Header.Get("X-Request-ID"); requestID != "" {
+		fields["request_id"] = requestID
+	}
+	return fields
+}
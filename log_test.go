@@ -0,0 +1,86 @@
+package rest_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/deluan/rest"
+	"github.com/deluan/rest/examples"
+	"github.com/deluan/rest/logrusadapter"
+	"github.com/sirupsen/logrus"
+	"github.com/sirupsen/logrus/hooks/test"
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestController_StructuredLogger(t *testing.T) {
+	Convey("Given a handler with a Logrus StructuredLogger", t, func() {
+		l, hook := test.NewNullLogger()
+		l.SetLevel(logrus.DebugLevel)
+		repo := examples.NewPersistableSampleRepository(nil)
+		handler := rest.Get(func(ctx context.Context) rest.Repository { return repo }, logrusadapter.New(l))
+
+		Convey("When I call Get for a missing record", func() {
+			req, res := createRequestResponse("GET", "/sample?:id=1", nil)
+			req.Header.Set("X-Request-ID", "abc")
+			handler(res, req)
+			So(res.Code, ShouldEqual, 404)
+
+			Convey("It logs a debug trace", func() {
+				So(hook.Entries, ShouldHaveLength, 2)
+				So(hook.Entries[0].Level, ShouldEqual, logrus.DebugLevel)
+				So(hook.Entries[0].Message, ShouldEqual, "Reading sample(id:1)")
+			})
+
+			Convey("It logs a warning with the request-scoped fields", func() {
+				entry := hook.LastEntry()
+				So(entry.Level, ShouldEqual, logrus.WarnLevel)
+				So(entry.Message, ShouldEqual, "Reading sample(id:1): data not found")
+				So(entry.Data, ShouldResemble, logrus.Fields{
+					"method":     "GET",
+					"path":       "/sample",
+					"entity":     "sample",
+					"id":         "1",
+					"request_id": "abc",
+				})
+			})
+		})
+
+		Convey("When I call GetAll with invalid params", func() {
+			handler := rest.GetAll(func(ctx context.Context) rest.Repository { return repo }, logrusadapter.New(l))
+			req, res := createRequestResponse("GET", "/sample?_filters=INVALID", nil)
+			req.Header.Set("X-Request-ID", "abc")
+			handler(res, req)
+			So(res.Code, ShouldEqual, 200)
+
+			Convey("It logs the warning with the request-scoped fields", func() {
+				var warning *logrus.Entry
+				for i := range hook.Entries {
+					if hook.Entries[i].Level == logrus.WarnLevel {
+						warning = &hook.Entries[i]
+					}
+				}
+				So(warning, ShouldNotBeNil)
+				So(warning.Message, ShouldStartWith, "Invalid filter specification")
+				So(warning.Data["path"], ShouldEqual, "/sample")
+				So(warning.Data["request_id"], ShouldEqual, "abc")
+			})
+		})
+	})
+
+	Convey("Given a handler with a plain Logger", t, func() {
+		log := &recordingLogger{}
+		repo := examples.NewPersistableSampleRepository(nil)
+		handler := rest.Get(func(ctx context.Context) rest.Repository { return repo }, log)
+
+		Convey("When I call Get", func() {
+			req, res := createRequestResponse("GET", "/sample?:id=1", nil)
+			handler(res, req)
+
+			Convey("It still uses Warnf and Errorf, discarding debug traces", func() {
+				So(res.Code, ShouldEqual, 404)
+				So(log.warnings, ShouldResemble, []string{"Reading sample(id:1): data not found"})
+				So(log.errors, ShouldBeEmpty)
+			})
+		})
+	})
+}
@@ -0,0 +1,37 @@
+/*
+Package logrusadapter provides a rest.StructuredLogger that writes to Logrus, so the core package does not depend on
+it. Ex:
+
+	rest.Get(NewThingsRepository, logrusadapter.New(logrus.StandardLogger()))
+*/
+package logrusadapter
+
+import (
+	"context"
+
+	"github.com/deluan/rest"
+	"github.com/sirupsen/logrus"
+)
+
+// New returns a StructuredLogger that writes to a Logrus Logger or Entry
+func New(logger logrus.FieldLogger) rest.StructuredLogger {
+	return logrusLogger{logger}
+}
+
+type logrusLogger struct {
+	logrus.FieldLogger
+}
+
+func (l logrusLogger) Log(ctx context.Context, level rest.Level, msg string, fields rest.Fields) {
+	entry := l.WithFields(logrus.Fields(fields))
+	switch level {
+	case rest.LevelDebug:
+		entry.Debug(msg)
+	case rest.LevelInfo:
+		entry.Info(msg)
+	case rest.LevelWarn:
+		entry.Warn(msg)
+	default:
+		entry.Error(msg)
+	}
+}
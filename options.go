@@ -0,0 +1,109 @@
+package rest
+
+/*
+Option configures a Controller, overriding the package defaults for that controller only. Options are used by the
+Handlers returned by Configure, and by NewController. Ex:
+
+	rest.Configure(rest.WithLogger(logger), rest.WithMaxPageSize(100)).GetAll(NewThingsRepository)
+*/
+type Option func(c *Controller)
+
+// WithLogger sets the Logger used by the controller. It is the same as passing the Logger to the handler functions
+func WithLogger(logger Logger) Option {
+	return func(c *Controller) {
+		c.Logger = logger
+	}
+}
+
+// WithMaxPageSize limits the number of entities returned by GetAll, even if the client does not specify a limit
+func WithMaxPageSize(size int) Option {
+	return func(c *Controller) {
+		c.MaxPageSize = size
+	}
+}
+
+// WithIDExtractor sets the IDExtractor used by the controller, instead of the one set with SetIDExtractor
+func WithIDExtractor(extractor IDExtractor) Option {
+	return func(c *Controller) {
+		c.IDExtractor = extractor
+	}
+}
+
+// WithErrorMapper adds an ErrorMapper to the controller, tried before the ones registered with RegisterErrorMapper
+func WithErrorMapper(mapper ErrorMapper) Option {
+	return func(c *Controller) {
+		c.ErrorMappers = append(c.ErrorMappers, mapper)
+	}
+}
+
+// WithRenderer adds a renderer to the controller under the specified format name, replacing the one registered with
+// RegisterRenderer with the same name, if any
+func WithRenderer(format string, renderer Renderer) Option {
+	return func(c *Controller) {
+		for i, rr := range c.renderers {
+			if rr.format == format {
+				c.renderers[i].renderer = renderer
+				return
+			}
+		}
+		c.renderers = append(c.renderers, registeredRenderer{format, renderer})
+	}
+}
+
+// WithProblemDetails sets the error format of the controller, instead of the one set with EnableProblemDetails
+func WithProblemDetails(enabled bool) Option {
+	return func(c *Controller) {
+		c.ProblemDetails = enabled
+	}
+}
+
+// WithErrorSanitizer sets the ErrorSanitizer used by the controller, instead of the one set with SetErrorSanitizer
+func WithErrorSanitizer(sanitizer ErrorSanitizer) Option {
+	return func(c *Controller) {
+		c.ErrorSanitizer = sanitizer
+	}
+}
+
+// WithMaxBodySize rejects request bodies bigger than size bytes with a 413 http status
+func WithMaxBodySize(size int64) Option {
+	return func(c *Controller) {
+		c.MaxBodySize = size
+	}
+}
+
+// WithStrictJSON makes Post and Put reject entities with fields that are not present in the entity type
+func WithStrictJSON(enabled bool) Option {
+	return func(c *Controller) {
+		c.StrictJSON = enabled
+	}
+}
+
+// WithSortTieBreaker sets the field used as a sorting tie-breaker, for repositories whose key is not named IDField
+func WithSortTieBreaker(field string) Option {
+	return func(c *Controller) {
+		c.SortTieBreaker = field
+	}
+}
+
+/*
+NewController returns a Controller for the repository, configured with the package defaults and the options. Ex:
+
+	c := rest.NewController(repo, rest.WithLogger(logger), rest.WithProblemDetails(true))
+*/
+func NewController(repository Repository, options ...Option) *Controller {
+	c := &Controller{
+		Repository:     repository,
+		ProblemDetails: problemDetailsEnabled(),
+		ErrorSanitizer: defaultErrorSanitizer(),
+		IDExtractor:    defaultIDExtractor(),
+	}
+	c.Apply(options...)
+	return c
+}
+
+// Apply configures the controller with the options
+func (c *Controller) Apply(options ...Option) {
+	for _, option := range options {
+		option(c)
+	}
+}
@@ -0,0 +1,183 @@
+package rest_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"testing"
+
+	"github.com/deluan/rest"
+	"github.com/deluan/rest/examples"
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+type textRenderer struct{}
+
+func (textRenderer) ContentType() string { return "text/plain" }
+
+func (textRenderer) Render(w io.Writer, payload interface{}) error {
+	_, err := fmt.Fprintf(w, "%v", payload)
+	return err
+}
+
+// optionsRecorder keeps the options received by ReadAll
+type optionsRecorder struct {
+	rest.Repository
+	options rest.QueryOptions
+}
+
+func (r *optionsRecorder) ReadAll(options ...rest.QueryOptions) (interface{}, error) {
+	r.options = options[0]
+	return r.Repository.ReadAll(options...)
+}
+
+func TestHandlerOptions(t *testing.T) {
+	Convey("Given a repository with three items", t, func() {
+		repo := examples.NewPersistableSampleRepository(nil)
+		for _, name := range []string{"Joe", "Cecilia", "Paul"} {
+			record := aRecord(name, 30)
+			_, _ = repo.Save(&record)
+		}
+		newRepository := func(ctx context.Context) rest.Repository { return repo }
+
+		Convey("When I pass a Logger to the handler function", func() {
+			log := &recordingLogger{}
+			req, res := createRequestResponse("GET", "/sample?:id=NOT_FOUND", nil)
+			rest.Get(newRepository, log)(res, req)
+
+			Convey("It is used by the controller", func() {
+				So(res.Code, ShouldEqual, 404)
+				So(log.warnings, ShouldHaveLength, 1)
+			})
+		})
+
+		Convey("When I configure the handlers WithLogger", func() {
+			log := &recordingLogger{}
+			req, res := createRequestResponse("GET", "/sample?:id=NOT_FOUND", nil)
+			rest.Configure(rest.WithLogger(log)).Get(newRepository)(res, req)
+
+			Convey("It is used by the controller", func() {
+				So(res.Code, ShouldEqual, 404)
+				So(log.warnings, ShouldHaveLength, 1)
+			})
+		})
+
+		Convey("When I configure the handlers WithMaxPageSize", func() {
+			recorder := &optionsRecorder{Repository: repo}
+			handler := rest.Configure(rest.WithLogger(logger), rest.WithMaxPageSize(2)).
+				GetAll(func(ctx context.Context) rest.Repository { return recorder })
+
+			Convey("And the client does not specify a limit", func() {
+				req, res := createRequestResponse("GET", "/sample", nil)
+				handler(res, req)
+
+				Convey("It limits the page to the max page size", func() {
+					So(recorder.options.Max, ShouldEqual, 2)
+					So(res.Header().Get("Link"), ShouldContainSubstring, `rel="next"`)
+				})
+			})
+
+			Convey("And the client specifies a bigger limit", func() {
+				req, res := createRequestResponse("GET", "/sample?_limit=10", nil)
+				handler(res, req)
+
+				Convey("It limits the page to the max page size", func() {
+					So(recorder.options.Max, ShouldEqual, 2)
+				})
+			})
+
+			Convey("And the client specifies a smaller limit", func() {
+				req, res := createRequestResponse("GET", "/sample?_limit=1", nil)
+				handler(res, req)
+
+				Convey("It keeps the client's limit", func() {
+					So(recorder.options.Max, ShouldEqual, 1)
+				})
+			})
+		})
+
+		Convey("When I configure the handlers WithIDExtractor", func() {
+			req, res := createRequestResponse("GET", "/sample?id=NOT_FOUND", nil)
+			rest.Configure(rest.WithLogger(logger), rest.WithIDExtractor(rest.QueryID("id"))).Get(newRepository)(res, req)
+
+			Convey("It is used instead of the default one", func() {
+				So(res.Code, ShouldEqual, 404)
+				So(res.Body.String(), ShouldContainSubstring, "NOT_FOUND")
+			})
+		})
+
+		Convey("When I configure the handlers WithErrorMapper", func() {
+			req, res := createRequestResponse("GET", "/sample?:id=NOT_FOUND", nil)
+			mapper := rest.MapError(rest.ErrNotFound, http.StatusGone)
+			rest.Configure(rest.WithLogger(logger), rest.WithErrorMapper(mapper)).Get(newRepository)(res, req)
+
+			Convey("It is tried before the registered mappers", func() {
+				So(res.Code, ShouldEqual, http.StatusGone)
+			})
+		})
+
+		Convey("When I configure the handlers WithRenderer", func() {
+			handler := rest.Configure(rest.WithLogger(logger), rest.WithRenderer("text", textRenderer{})).GetAll(newRepository)
+			req, res := createRequestResponse("GET", "/sample?_format=text", nil)
+			handler(res, req)
+
+			Convey("It can be selected by the client", func() {
+				So(res.Code, ShouldEqual, 200)
+				So(res.Header().Get("Content-Type"), ShouldEqual, "text/plain")
+			})
+
+			Convey("It is not available to other controllers", func() {
+				req, res := createRequestResponse("GET", "/sample?_format=text", nil)
+				rest.GetAll(newRepository, logger)(res, req)
+				So(res.Code, ShouldEqual, http.StatusNotAcceptable)
+			})
+		})
+
+		Convey("When I configure the handlers WithProblemDetails", func() {
+			req, res := createRequestResponse("GET", "/sample?:id=NOT_FOUND", nil)
+			rest.Configure(rest.WithLogger(logger), rest.WithProblemDetails(true)).Get(newRepository)(res, req)
+
+			Convey("It sends errors as Problems", func() {
+				So(res.Header().Get("Content-Type"), ShouldEqual, "application/problem+json")
+			})
+		})
+
+		Convey("When I configure the handlers WithErrorSanitizer", func() {
+			repo.Error = errors.New("connection refused")
+			req, res := createRequestResponse("GET", "/sample?:id=1", nil)
+			rest.Configure(rest.WithLogger(logger), rest.WithErrorSanitizer(rest.ProductionErrors)).Get(newRepository)(res, req)
+
+			Convey("It hides internal errors", func() {
+				So(res.Code, ShouldEqual, 500)
+				So(res.Body.String(), ShouldNotContainSubstring, "connection refused")
+				So(res.Header().Get("X-Correlation-ID"), ShouldNotBeEmpty)
+			})
+		})
+
+		Convey("When I use the Handlers to create a Resource", func() {
+			resource := rest.Configure(rest.WithLogger(logger), rest.WithProblemDetails(true)).Resource(newRepository)
+			req, res := createRequestResponse("GET", "/NOT_FOUND", nil)
+			resource.ServeHTTP(res, req)
+
+			Convey("It applies the options", func() {
+				So(res.Code, ShouldEqual, 404)
+				So(res.Header().Get("Content-Type"), ShouldEqual, "application/problem+json")
+			})
+		})
+
+		Convey("When I create a controller with NewController", func() {
+			recorder := &optionsRecorder{Repository: repo}
+			c := rest.NewController(recorder, rest.WithLogger(logger), rest.WithMaxPageSize(1))
+			req, res := createRequestResponse("GET", "/sample", nil)
+			c.GetAll(res, req)
+
+			Convey("It applies the options", func() {
+				So(res.Code, ShouldEqual, 200)
+				So(recorder.options.Max, ShouldEqual, 1)
+				So(c.Logger, ShouldEqual, logger)
+			})
+		})
+	})
+}
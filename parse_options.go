@@ -0,0 +1,235 @@
+package rest
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Suffixes recognized in filter params, following the JSON Server conventions. Ex: age_gte=30
+var filterOperators = []FilterOperator{OpGte, OpLte, OpNe, OpLike, OpIn, OpNin}
+
+func (c *Controller) parseFilters(r *http.Request, params url.Values) map[string]interface{} {
+	var filterStr = params.Get("_filters")
+	filters := make(map[string]interface{})
+	if filterStr != "" {
+		filterStr, _ = url.QueryUnescape(filterStr)
+		if err := json.Unmarshal([]byte(filterStr), &filters); err != nil {
+			c.warnf(r, "Invalid filter specification: %s - %v", filterStr, err)
+		}
+	}
+	for k, v := range params {
+		if strings.HasPrefix(k, "_") {
+			continue
+		}
+		if len(v) == 1 {
+			filters[k] = v[0]
+		} else {
+			filters[k] = v
+		}
+	}
+	return filters
+}
+
+// parseSearch removes the full-text search param (q) from the filters, returning its value
+func (c *Controller) parseSearch(filters map[string]interface{}) string {
+	q, ok := filters["q"]
+	if !ok {
+		return ""
+	}
+	delete(filters, "q")
+	return strings.Join(filterValues(q), " ")
+}
+
+// parseFilterList converts the raw filters map into a list of Filters, sorted by param name
+func (c *Controller) parseFilterList(filters map[string]interface{}) []Filter {
+	keys := make([]string, 0, len(filters))
+	for k := range filters {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	list := make([]Filter, 0, len(keys))
+	for _, k := range keys {
+		field, op := splitFilterOperator(k)
+		values := filterValues(filters[k])
+		if op == OpIn || op == OpNin {
+			values = splitFilterValues(values)
+		}
+		list = append(list, Filter{Field: field, Operator: op, Values: values})
+	}
+	return list
+}
+
+func splitFilterOperator(param string) (string, FilterOperator) {
+	for _, op := range filterOperators {
+		suffix := "_" + string(op)
+		if strings.HasSuffix(param, suffix) && len(param) > len(suffix) {
+			return strings.TrimSuffix(param, suffix), op
+		}
+	}
+	return param, OpEq
+}
+
+func filterValues(value interface{}) []string {
+	switch v := value.(type) {
+	case []string:
+		return v
+	case []interface{}:
+		values := make([]string, 0, len(v))
+		for _, e := range v {
+			values = append(values, filterValues(e)...)
+		}
+		return values
+	case string:
+		return []string{v}
+	case nil:
+		return nil
+	default:
+		return []string{fmt.Sprint(v)}
+	}
+}
+
+// splitFilterValues accepts both repeated params (id_in=1&id_in=2) and comma separated lists (id_in=1,2)
+func splitFilterValues(values []string) []string {
+	var result []string
+	for _, v := range values {
+		result = append(result, strings.Split(v, ",")...)
+	}
+	return result
+}
+
+// Page size used when _page is specified without _limit, same as JSON Server
+const defaultPageSize = 10
+
+/*
+parsePagination returns the offset and max records to return. The following combinations are accepted:
+
+	_start=20&_end=30   -> offset 20, max 10
+	_start=20&_limit=10 -> offset 20, max 10
+	_page=3&_limit=10   -> offset 20, max 10 (pages start at 1. If _limit is omitted, defaults to 10)
+	_limit=10           -> offset 0, max 10
+
+When both styles are mixed, _start/_end win and _page is ignored. If both _end and _limit are specified, _end wins.
+Negative and invalid values are ignored. If the controller has a MaxPageSize, max is capped by it.
+*/
+func (c *Controller) parsePagination(r *http.Request, params url.Values) (offset int, max int) {
+	start, hasStart := intParam(params, "_start")
+	end, hasEnd := intParam(params, "_end")
+	page, hasPage := intParam(params, "_page")
+	limit, hasLimit := intParam(params, "_limit")
+
+	switch {
+	case hasStart || hasEnd:
+		offset = start
+		if hasEnd {
+			max = end - start
+		} else if hasLimit {
+			max = limit
+		}
+		if hasPage {
+			c.warnf(r, "Both _page and _start/_end pagination params specified. Ignoring _page=%d", page)
+		}
+	case hasPage:
+		if !hasLimit {
+			limit = defaultPageSize
+		}
+		offset = (int(math.Max(1, float64(page))) - 1) * limit
+		max = limit
+	case hasLimit:
+		max = limit
+	}
+	max = int(math.Max(0, float64(max)))
+	if c.MaxPageSize > 0 && (max == 0 || max > c.MaxPageSize) {
+		max = c.MaxPageSize
+	}
+	return offset, max
+}
+
+func intParam(params url.Values, name string) (int, bool) {
+	value, err := strconv.Atoi(params.Get(name))
+	if err != nil || value < 0 {
+		return 0, false
+	}
+	return value, true
+}
+
+/*
+parseSort converts the _sort and _order params into a list of SortFields. Fields are matched to the directions in
+_order by position. If _order has a single value, it applies to all fields. Fields prefixed with "-" are always sorted
+in descending order. Ex:
+
+	_sort=name,age&_order=asc,desc -> name asc, age desc, id asc
+	_sort=name,age&_order=desc     -> name desc, age desc, id asc
+	_sort=-age,name                -> age desc, name asc, id asc
+*/
+func (c *Controller) parseSort(sort string, order string) []SortField {
+	if strings.TrimSpace(sort) == "" {
+		return nil
+	}
+	var orders []string
+	if order != "" {
+		orders = strings.Split(strings.ToLower(order), ",")
+	}
+
+	var fields []SortField
+	hasID := false
+	for i, f := range strings.Split(sort, ",") {
+		f = strings.TrimSpace(f)
+		desc := false
+		switch {
+		case len(orders) == 1:
+			desc = strings.TrimSpace(orders[0]) == "desc"
+		case i < len(orders):
+			desc = strings.TrimSpace(orders[i]) == "desc"
+		}
+		if strings.HasPrefix(f, "-") {
+			f = f[1:]
+			desc = true
+		}
+		if f == "" {
+			continue
+		}
+		hasID = hasID || f == c.sortTieBreaker()
+		fields = append(fields, SortField{Field: f, Desc: desc})
+	}
+	if len(fields) > 0 && !hasID {
+		fields = append(fields, SortField{Field: c.sortTieBreaker()})
+	}
+	return fields
+}
+
+// parseOptions converts the query params of the request into QueryOptions. r is used only for logging, and can be nil
+func (c *Controller) parseOptions(r *http.Request, params url.Values) QueryOptions {
+	offset, max := c.parsePagination(r, params)
+
+	sortField := params.Get("_sort")
+	sortDir := params.Get("_order")
+	sortFields := c.parseSort(sortField, sortDir)
+
+	filters := c.parseFilters(r, params)
+	search := c.parseSearch(filters)
+	return QueryOptions{
+		Sort:       sortField,
+		Order:      strings.ToLower(sortDir),
+		SortFields: sortFields,
+		Offset:     offset,
+		Max:        max,
+		Search:     search,
+		Filters:    filters,
+		FilterList: c.parseFilterList(filters),
+	}
+}
+
+// sortTieBreaker returns the field appended to the SortFields, to make the order deterministic
+func (c *Controller) sortTieBreaker() string {
+	if c.SortTieBreaker == "" {
+		return IDField
+	}
+	return c.SortTieBreaker
+}
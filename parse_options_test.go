@@ -14,31 +14,97 @@ func Test_parseOptions(t *testing.T) {
 	c := &Controller{Logger: logger}
 
 	Convey("Given no params", t, func() {
-		options := c.parseOptions(url.Values{})
+		options := c.parseOptions(nil, url.Values{})
 		Convey("It returns an empty QueryOptions struct", func() {
 			So(options.Sort, ShouldBeEmpty)
 			So(options.Order, ShouldBeEmpty)
 			So(options.Offset, ShouldEqual, 0)
 			So(options.Max, ShouldEqual, 0)
 			So(options.Filters, ShouldBeEmpty)
+			So(options.SortFields, ShouldBeEmpty)
 		})
 	})
 
 	Convey("Given pagination params", t, func() {
 		params := url.Values{"_start": []string{"10"}, "_end": []string{"30"}, "_sort": []string{"name"}, "_order": []string{"DESC"}}
-		options := c.parseOptions(params)
+		options := c.parseOptions(nil, params)
 
 		Convey("it  returns a proper filled QueryOptions struct", func() {
 			So(options.Sort, ShouldEqual, "name")
 			So(options.Order, ShouldEqual, "desc")
 			So(options.Offset, ShouldEqual, 10)
 			So(options.Max, ShouldEqual, 20)
+			So(options.SortFields, ShouldResemble, []SortField{{Field: "name", Desc: true}, {Field: "id"}})
+		})
+	})
+
+	Convey("Given page based pagination params", t, func() {
+		params := url.Values{"_page": []string{"3"}, "_limit": []string{"15"}}
+		options := c.parseOptions(nil, params)
+
+		Convey("it converts them to offset and max", func() {
+			So(options.Offset, ShouldEqual, 30)
+			So(options.Max, ShouldEqual, 15)
+		})
+
+		Convey("it does not add them to the filters", func() {
+			So(options.Filters, ShouldBeEmpty)
+		})
+	})
+
+	Convey("Given a _page param without _limit", t, func() {
+		params := url.Values{"_page": []string{"2"}}
+		options := c.parseOptions(nil, params)
+
+		Convey("it uses the default page size", func() {
+			So(options.Offset, ShouldEqual, 10)
+			So(options.Max, ShouldEqual, 10)
+		})
+	})
+
+	Convey("Given an invalid _page param", t, func() {
+		params := url.Values{"_page": []string{"0"}, "_limit": []string{"5"}}
+		options := c.parseOptions(nil, params)
+
+		Convey("it returns the first page", func() {
+			So(options.Offset, ShouldEqual, 0)
+			So(options.Max, ShouldEqual, 5)
+		})
+	})
+
+	Convey("Given _start and _limit params", t, func() {
+		params := url.Values{"_start": []string{"5"}, "_limit": []string{"20"}}
+		options := c.parseOptions(nil, params)
+
+		Convey("it uses _limit as the max", func() {
+			So(options.Offset, ShouldEqual, 5)
+			So(options.Max, ShouldEqual, 20)
+		})
+	})
+
+	Convey("Given mixed pagination params", t, func() {
+		params := url.Values{"_start": []string{"10"}, "_end": []string{"30"}, "_page": []string{"5"}, "_limit": []string{"50"}}
+		options := c.parseOptions(nil, params)
+
+		Convey("_start and _end win", func() {
+			So(options.Offset, ShouldEqual, 10)
+			So(options.Max, ShouldEqual, 20)
+		})
+	})
+
+	Convey("Given an _end smaller than _start", t, func() {
+		params := url.Values{"_start": []string{"30"}, "_end": []string{"10"}}
+		options := c.parseOptions(nil, params)
+
+		Convey("it returns no records", func() {
+			So(options.Offset, ShouldEqual, 30)
+			So(options.Max, ShouldEqual, 0)
 		})
 	})
 
 	Convey("Given individual filter params", t, func() {
 		params := url.Values{"name": []string{"joe"}, "age": []string{"30"}}
-		options := c.parseOptions(params)
+		options := c.parseOptions(nil, params)
 
 		Convey("it  returns a proper filled QueryOptions struct", func() {
 			So(options.Filters, ShouldHaveLength, 2)
@@ -49,7 +115,7 @@ func Test_parseOptions(t *testing.T) {
 
 	Convey("Given duplicated individual filter params", t, func() {
 		params := url.Values{"name": []string{"joe", "cecilia"}}
-		options := c.parseOptions(params)
+		options := c.parseOptions(nil, params)
 
 		Convey("it  returns a proper filled QueryOptions struct", func() {
 			So(options.Filters, ShouldHaveLength, 1)
@@ -59,7 +125,7 @@ func Test_parseOptions(t *testing.T) {
 
 	Convey("Given single filter param", t, func() {
 		params := url.Values{"_filters": []string{`{"name":"cecilia","age":"22"}`}}
-		options := c.parseOptions(params)
+		options := c.parseOptions(nil, params)
 
 		Convey("it returns a proper filled QueryOptions struct", func() {
 			So(options.Filters, ShouldHaveLength, 2)
@@ -70,7 +136,7 @@ func Test_parseOptions(t *testing.T) {
 
 	Convey("Given an invalid single filter param", t, func() {
 		params := url.Values{"_filters": []string{`{"name":"cecilia","age":MISSING_QUOTES}`}}
-		options := c.parseOptions(params)
+		options := c.parseOptions(nil, params)
 
 		Convey("it ignores the filter", func() {
 			So(options.Filters, ShouldHaveLength, 0)
@@ -78,6 +144,167 @@ func Test_parseOptions(t *testing.T) {
 	})
 }
 
+func Test_parseSort(t *testing.T) {
+	c := &Controller{Logger: logger}
+
+	Convey("Given multiple sort fields and directions", t, func() {
+		params := url.Values{"_sort": []string{"name,age"}, "_order": []string{"ASC,DESC"}}
+		options := c.parseOptions(nil, params)
+
+		Convey("it returns the fields with their directions, plus the id tie-breaker", func() {
+			So(options.SortFields, ShouldResemble, []SortField{
+				{Field: "name"}, {Field: "age", Desc: true}, {Field: "id"},
+			})
+		})
+
+		Convey("it keeps the raw params", func() {
+			So(options.Sort, ShouldEqual, "name,age")
+			So(options.Order, ShouldEqual, "asc,desc")
+		})
+	})
+
+	Convey("Given multiple sort fields and a single direction", t, func() {
+		params := url.Values{"_sort": []string{"name,age"}, "_order": []string{"desc"}}
+		options := c.parseOptions(nil, params)
+
+		Convey("it applies the direction to all fields", func() {
+			So(options.SortFields, ShouldResemble, []SortField{
+				{Field: "name", Desc: true}, {Field: "age", Desc: true}, {Field: "id"},
+			})
+		})
+	})
+
+	Convey("Given sort fields with the - prefix", t, func() {
+		params := url.Values{"_sort": []string{"-age, name"}}
+		options := c.parseOptions(nil, params)
+
+		Convey("it sorts these fields in descending order", func() {
+			So(options.SortFields, ShouldResemble, []SortField{
+				{Field: "age", Desc: true}, {Field: "name"}, {Field: "id"},
+			})
+		})
+	})
+
+	Convey("Given the id is one of the sort fields", t, func() {
+		params := url.Values{"_sort": []string{"-id,name"}}
+		options := c.parseOptions(nil, params)
+
+		Convey("it does not add the tie-breaker", func() {
+			So(options.SortFields, ShouldResemble, []SortField{{Field: "id", Desc: true}, {Field: "name"}})
+		})
+	})
+
+	Convey("Given a controller with a custom tie-breaker", t, func() {
+		c := &Controller{Logger: logger, SortTieBreaker: "uuid"}
+
+		Convey("it appends the custom field", func() {
+			options := c.parseOptions(nil, url.Values{"_sort": []string{"name"}})
+			So(options.SortFields, ShouldResemble, []SortField{{Field: "name"}, {Field: "uuid"}})
+		})
+
+		Convey("it does not add it when it is one of the sort fields", func() {
+			options := c.parseOptions(nil, url.Values{"_sort": []string{"uuid,name"}})
+			So(options.SortFields, ShouldResemble, []SortField{{Field: "uuid"}, {Field: "name"}})
+		})
+	})
+}
+
+func Test_parseSearch(t *testing.T) {
+	c := &Controller{Logger: logger}
+
+	Convey("Given a q param", t, func() {
+		params := url.Values{"q": []string{"joe"}, "age": []string{"30"}}
+		options := c.parseOptions(nil, params)
+
+		Convey("it sets the Search field", func() {
+			So(options.Search, ShouldEqual, "joe")
+		})
+
+		Convey("it does not add q to the filters", func() {
+			So(options.Filters, ShouldHaveLength, 1)
+			So(options.Filters, ShouldNotContainKey, "q")
+			So(options.FilterList, ShouldHaveLength, 1)
+			So(options.FilterList[0].Field, ShouldEqual, "age")
+		})
+	})
+
+	Convey("Given a q inside the single filter param", t, func() {
+		params := url.Values{"_filters": []string{`{"q":"cecilia"}`}}
+		options := c.parseOptions(nil, params)
+
+		Convey("it sets the Search field", func() {
+			So(options.Search, ShouldEqual, "cecilia")
+			So(options.Filters, ShouldBeEmpty)
+		})
+	})
+}
+
+func Test_parseFilterList(t *testing.T) {
+	c := &Controller{Logger: logger}
+
+	Convey("Given filter params with operator suffixes", t, func() {
+		params := url.Values{
+			"age_gte":   []string{"30"},
+			"age_lte":   []string{"40"},
+			"name_like": []string{"jo"},
+			"id_ne":     []string{"1"},
+			"city":      []string{"Paris"},
+		}
+		options := c.parseOptions(nil, params)
+
+		Convey("it returns the operators parsed, sorted by param name", func() {
+			So(options.FilterList, ShouldResemble, []Filter{
+				{Field: "age", Operator: OpGte, Values: []string{"30"}},
+				{Field: "age", Operator: OpLte, Values: []string{"40"}},
+				{Field: "city", Operator: OpEq, Values: []string{"Paris"}},
+				{Field: "id", Operator: OpNe, Values: []string{"1"}},
+				{Field: "name", Operator: OpLike, Values: []string{"jo"}},
+			})
+		})
+
+		Convey("it keeps the raw params in the Filters map", func() {
+			So(options.Filters, ShouldHaveLength, 5)
+			So(options.Filters["age_gte"], ShouldEqual, "30")
+		})
+	})
+
+	Convey("Given _in and _nin filter params", t, func() {
+		params := url.Values{"id_in": []string{"1,2", "3"}, "status_nin": []string{"closed"}}
+		options := c.parseOptions(nil, params)
+
+		Convey("it splits the values", func() {
+			So(options.FilterList, ShouldResemble, []Filter{
+				{Field: "id", Operator: OpIn, Values: []string{"1", "2", "3"}},
+				{Field: "status", Operator: OpNin, Values: []string{"closed"}},
+			})
+		})
+	})
+
+	Convey("Given a param that is only an operator suffix", t, func() {
+		params := url.Values{"_like": []string{"x"}, "like": []string{"y"}}
+		options := c.parseOptions(nil, params)
+
+		Convey("it is treated as a regular field", func() {
+			So(options.FilterList, ShouldResemble, []Filter{
+				{Field: "like", Operator: OpEq, Values: []string{"y"}},
+			})
+		})
+	})
+
+	Convey("Given a single filter param with operators", t, func() {
+		params := url.Values{"_filters": []string{`{"age_gte":30,"tags_in":["a","b"]}`}}
+		options := c.parseOptions(nil, params)
+
+		Convey("it returns the operators parsed", func() {
+			So(options.FilterList, ShouldResemble, []Filter{
+				{Field: "age", Operator: OpGte, Values: []string{"30"}},
+				{Field: "tags", Operator: OpIn, Values: []string{"a", "b"}},
+			})
+			So(options.FilterList[0].Value(), ShouldEqual, "30")
+		})
+	})
+}
+
 func init() {
 	logger.SetLevel(logrus.FatalLevel)
 }
@@ -0,0 +1,470 @@
+package rest
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Media types accepted by the PATCH handler
+const (
+	mediaTypeJSON       = "application/json"
+	mediaTypeMergePatch = "application/merge-patch+json"
+	mediaTypeJSONPatch  = "application/json-patch+json"
+)
+
+// errPatchTestFailed is returned when a JSON Patch test operation fails
+var errPatchTestFailed = errors.New("test operation failed")
+
+/*
+applyPatch applies the patch to the document, using the format specified by mediaType. JSON Patch (RFC 6902) is used
+for application/json-patch+json, and JSON Merge Patch (RFC 7396) for all others. It returns the patched document and
+the paths of the fields changed.
+*/
+func applyPatch(mediaType string, doc interface{}, patch interface{}) (interface{}, []string, error) {
+	if mediaType == mediaTypeJSONPatch {
+		ops, ok := patch.([]interface{})
+		if !ok {
+			return nil, nil, errors.New("JSON Patch must be an array of operations")
+		}
+		return jsonPatch(doc, ops)
+	}
+	if _, ok := patch.(map[string]interface{}); !ok {
+		return nil, nil, errors.New("JSON Merge Patch must be an object")
+	}
+	patched, changed := mergePatch(doc, patch)
+	return patched, changed, nil
+}
+
+/*
+mergePatch applies a JSON Merge Patch (RFC 7396) to the target document, returning the patched document and the
+paths of the fields changed. Nested fields are represented in the paths using dots. Ex: "address.city". Patched
+objects are merged recursively, and null values remove the corresponding fields from the target.
+*/
+func mergePatch(target interface{}, patch interface{}) (interface{}, []string) {
+	var changed []string
+	result := mergeValue(target, patch, nil, &changed)
+	return result, changed
+}
+
+func mergeValue(target interface{}, patch interface{}, path []string, changed *[]string) interface{} {
+	patchObj, ok := patch.(map[string]interface{})
+	if !ok {
+		if !reflect.DeepEqual(target, patch) {
+			*changed = append(*changed, strings.Join(path, "."))
+		}
+		return patch
+	}
+
+	targetObj, ok := target.(map[string]interface{})
+	if !ok {
+		// Target is not an object, so it is replaced by a new one. Any nulls in the patch are just removed
+		targetObj = map[string]interface{}{}
+		if len(path) > 0 {
+			*changed = append(*changed, strings.Join(path, "."))
+		}
+		changed = new([]string)
+	}
+	for _, k := range sortedFieldNames(patchObj) {
+		fieldPath := append(path[:len(path):len(path)], k)
+		v := patchObj[k]
+		if v == nil {
+			if _, exists := targetObj[k]; exists {
+				delete(targetObj, k)
+				*changed = append(*changed, strings.Join(fieldPath, "."))
+			}
+			continue
+		}
+		targetObj[k] = mergeValue(targetObj[k], v, fieldPath, changed)
+	}
+	return targetObj
+}
+
+func sortedFieldNames(m map[string]interface{}) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+/*
+jsonPatch applies the JSON Patch (RFC 6902) operations to the document, returning the patched document and the
+paths of the fields touched by the operations, in the same format used by mergePatch. Changes inside arrays are
+reported as changes to the whole array. If a test operation fails, it returns errPatchTestFailed. The document may
+be modified even if an error is returned.
+*/
+func jsonPatch(doc interface{}, ops []interface{}) (interface{}, []string, error) {
+	touched := map[string]bool{}
+	for i, o := range ops {
+		op, ok := o.(map[string]interface{})
+		if !ok {
+			return nil, nil, fmt.Errorf("operation %d is not an object", i)
+		}
+		var err error
+		doc, err = applyOperation(doc, op, touched)
+		if err == errPatchTestFailed {
+			return nil, nil, fmt.Errorf("%w: operation %d (path %v)", err, i, op["path"])
+		}
+		if err != nil {
+			return nil, nil, fmt.Errorf("invalid operation %d: %v", i, err)
+		}
+	}
+	changed := make([]string, 0, len(touched))
+	for k := range touched {
+		changed = append(changed, k)
+	}
+	sort.Strings(changed)
+	return doc, changed, nil
+}
+
+func applyOperation(doc interface{}, op map[string]interface{}, touched map[string]bool) (interface{}, error) {
+	path, err := operationPointer(op, "path")
+	if err != nil {
+		return nil, err
+	}
+	value, hasValue := op["value"]
+	name, _ := op["op"].(string)
+	if (name == "add" || name == "replace" || name == "test") && !hasValue {
+		return nil, fmt.Errorf("missing value for %s", name)
+	}
+
+	switch name {
+	case "add":
+		return touching(doc, path, touched, func() (interface{}, error) {
+			return addValue(doc, path, value)
+		})
+	case "remove":
+		return touching(doc, path, touched, func() (interface{}, error) {
+			return removeValue(doc, path)
+		})
+	case "replace":
+		return touching(doc, path, touched, func() (interface{}, error) {
+			return replaceValue(doc, path, value)
+		})
+	case "test":
+		current, err := getValue(doc, path)
+		if err != nil {
+			return nil, err
+		}
+		if !jsonEqual(current, value) {
+			return nil, errPatchTestFailed
+		}
+		return doc, nil
+	case "move", "copy":
+		from, err := operationPointer(op, "from")
+		if err != nil {
+			return nil, err
+		}
+		value, err := getValue(doc, from)
+		if err != nil {
+			return nil, err
+		}
+		if name == "move" {
+			if isPrefix(from, path) && len(from) < len(path) {
+				return nil, errors.New("cannot move a value into one of its children")
+			}
+			doc, err = touching(doc, from, touched, func() (interface{}, error) {
+				return removeValue(doc, from)
+			})
+			if err != nil {
+				return nil, err
+			}
+		} else {
+			value = deepCopy(value)
+		}
+		return touching(doc, path, touched, func() (interface{}, error) {
+			return addValue(doc, path, value)
+		})
+	}
+	return nil, fmt.Errorf("invalid op %v", op["op"])
+}
+
+// touching calls fn to change the document at path, marking the path as touched, both before and after the change.
+// If the whole document is changed, all its top level fields are marked
+func touching(doc interface{}, path []string, touched map[string]bool, fn func() (interface{}, error)) (interface{}, error) {
+	mark := func(doc interface{}) {
+		if len(path) > 0 {
+			touched[fieldPath(doc, path)] = true
+			return
+		}
+		if obj, ok := doc.(map[string]interface{}); ok {
+			for k := range obj {
+				touched[k] = true
+			}
+		}
+	}
+	mark(doc)
+	result, err := fn()
+	if err == nil {
+		mark(result)
+	}
+	return result, err
+}
+
+// operationPointer parses the JSON Pointer (RFC 6901) in the field of the operation into a list of reference tokens
+func operationPointer(op map[string]interface{}, field string) ([]string, error) {
+	pointer, ok := op[field].(string)
+	if !ok {
+		return nil, fmt.Errorf("missing %s", field)
+	}
+	if pointer == "" {
+		return []string{}, nil
+	}
+	if !strings.HasPrefix(pointer, "/") {
+		return nil, fmt.Errorf("invalid %s %q", field, pointer)
+	}
+	tokens := strings.Split(pointer[1:], "/")
+	for i, t := range tokens {
+		tokens[i] = strings.NewReplacer("~1", "/", "~0", "~").Replace(t)
+	}
+	return tokens, nil
+}
+
+// fieldPath returns the path of the field referenced by the tokens, in dotted notation. Paths into arrays are
+// truncated at the array
+func fieldPath(doc interface{}, tokens []string) string {
+	var path []string
+	for _, t := range tokens {
+		obj, ok := doc.(map[string]interface{})
+		if !ok {
+			break
+		}
+		path = append(path, t)
+		doc = obj[t]
+	}
+	return strings.Join(path, ".")
+}
+
+func getValue(doc interface{}, tokens []string) (interface{}, error) {
+	for _, t := range tokens {
+		switch c := doc.(type) {
+		case map[string]interface{}:
+			v, ok := c[t]
+			if !ok {
+				return nil, fmt.Errorf("path not found: %s", t)
+			}
+			doc = v
+		case []interface{}:
+			i, err := arrayIndex(t, len(c)-1)
+			if err != nil {
+				return nil, err
+			}
+			doc = c[i]
+		default:
+			return nil, fmt.Errorf("path not found: %s", t)
+		}
+	}
+	return doc, nil
+}
+
+func addValue(doc interface{}, tokens []string, value interface{}) (interface{}, error) {
+	if len(tokens) == 0 {
+		return value, nil
+	}
+	return updateParent(doc, tokens, func(parent interface{}, key string) (interface{}, error) {
+		switch c := parent.(type) {
+		case map[string]interface{}:
+			c[key] = value
+			return c, nil
+		case []interface{}:
+			i := len(c)
+			if key != "-" {
+				var err error
+				if i, err = arrayIndex(key, len(c)); err != nil {
+					return nil, err
+				}
+			}
+			c = append(c, nil)
+			copy(c[i+1:], c[i:])
+			c[i] = value
+			return c, nil
+		}
+		return nil, fmt.Errorf("path not found: %s", key)
+	})
+}
+
+func removeValue(doc interface{}, tokens []string) (interface{}, error) {
+	if len(tokens) == 0 {
+		return nil, errors.New("cannot remove the whole document")
+	}
+	return updateParent(doc, tokens, func(parent interface{}, key string) (interface{}, error) {
+		switch c := parent.(type) {
+		case map[string]interface{}:
+			if _, ok := c[key]; !ok {
+				return nil, fmt.Errorf("path not found: %s", key)
+			}
+			delete(c, key)
+			return c, nil
+		case []interface{}:
+			i, err := arrayIndex(key, len(c)-1)
+			if err != nil {
+				return nil, err
+			}
+			return append(c[:i], c[i+1:]...), nil
+		}
+		return nil, fmt.Errorf("path not found: %s", key)
+	})
+}
+
+func replaceValue(doc interface{}, tokens []string, value interface{}) (interface{}, error) {
+	if _, err := getValue(doc, tokens); err != nil {
+		return nil, err
+	}
+	if len(tokens) == 0 {
+		return value, nil
+	}
+	return updateParent(doc, tokens, func(parent interface{}, key string) (interface{}, error) {
+		switch c := parent.(type) {
+		case map[string]interface{}:
+			c[key] = value
+			return c, nil
+		case []interface{}:
+			i, _ := arrayIndex(key, len(c)-1)
+			c[i] = value
+			return c, nil
+		}
+		return nil, fmt.Errorf("path not found: %s", key)
+	})
+}
+
+// updateParent calls fn with the parent of the value referenced by tokens, replacing the parent with the value
+// returned by fn. As arrays may be reallocated, all containers in the path are updated
+func updateParent(doc interface{}, tokens []string, fn func(parent interface{}, key string) (interface{}, error)) (interface{}, error) {
+	if len(tokens) == 1 {
+		return fn(doc, tokens[0])
+	}
+	switch c := doc.(type) {
+	case map[string]interface{}:
+		child, ok := c[tokens[0]]
+		if !ok {
+			return nil, fmt.Errorf("path not found: %s", tokens[0])
+		}
+		child, err := updateParent(child, tokens[1:], fn)
+		if err != nil {
+			return nil, err
+		}
+		c[tokens[0]] = child
+		return c, nil
+	case []interface{}:
+		i, err := arrayIndex(tokens[0], len(c)-1)
+		if err != nil {
+			return nil, err
+		}
+		child, err := updateParent(c[i], tokens[1:], fn)
+		if err != nil {
+			return nil, err
+		}
+		c[i] = child
+		return c, nil
+	}
+	return nil, fmt.Errorf("path not found: %s", tokens[0])
+}
+
+// arrayIndex parses the token as an array index, between 0 and max
+func arrayIndex(token string, max int) (int, error) {
+	i, err := strconv.Atoi(token)
+	if err != nil || i < 0 || i > max || (len(token) > 1 && token[0] == '0') {
+		return 0, fmt.Errorf("invalid array index: %s", token)
+	}
+	return i, nil
+}
+
+func isPrefix(prefix []string, tokens []string) bool {
+	if len(prefix) > len(tokens) {
+		return false
+	}
+	for i := range prefix {
+		if prefix[i] != tokens[i] {
+			return false
+		}
+	}
+	return true
+}
+
+// jsonEqual compares two JSON values, considering numbers with different representations (ex: 1 and 1.0) equal
+func jsonEqual(a, b interface{}) bool {
+	switch va := a.(type) {
+	case json.Number:
+		vb, ok := b.(json.Number)
+		if !ok {
+			return false
+		}
+		fa, errA := va.Float64()
+		fb, errB := vb.Float64()
+		return errA == nil && errB == nil && fa == fb
+	case map[string]interface{}:
+		vb, ok := b.(map[string]interface{})
+		if !ok || len(va) != len(vb) {
+			return false
+		}
+		for k, v := range va {
+			if w, ok := vb[k]; !ok || !jsonEqual(v, w) {
+				return false
+			}
+		}
+		return true
+	case []interface{}:
+		vb, ok := b.([]interface{})
+		if !ok || len(va) != len(vb) {
+			return false
+		}
+		for i := range va {
+			if !jsonEqual(va[i], vb[i]) {
+				return false
+			}
+		}
+		return true
+	}
+	return reflect.DeepEqual(a, b)
+}
+
+func deepCopy(value interface{}) interface{} {
+	switch v := value.(type) {
+	case map[string]interface{}:
+		m := make(map[string]interface{}, len(v))
+		for k, e := range v {
+			m[k] = deepCopy(e)
+		}
+		return m
+	case []interface{}:
+		s := make([]interface{}, len(v))
+		for i, e := range v {
+			s[i] = deepCopy(e)
+		}
+		return s
+	}
+	return value
+}
+
+// toDocument converts the entity to its generic JSON representation (maps, slices and json.Numbers)
+func toDocument(entity interface{}) (interface{}, error) {
+	buf, err := json.Marshal(entity)
+	if err != nil {
+		return nil, err
+	}
+	var doc interface{}
+	err = decodeJSON(buf, &doc)
+	return doc, err
+}
+
+// fromDocument converts the generic JSON representation back to the entity
+func fromDocument(doc interface{}, entity interface{}) error {
+	buf, err := json.Marshal(doc)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(buf, entity)
+}
+
+func decodeJSON(data []byte, v interface{}) error {
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+	return decoder.Decode(v)
+}
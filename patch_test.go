@@ -0,0 +1,153 @@
+package rest
+
+import (
+	"errors"
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func Test_mergePatch(t *testing.T) {
+	parse := func(s string) interface{} {
+		var v interface{}
+		if err := decodeJSON([]byte(s), &v); err != nil {
+			panic(err)
+		}
+		return v
+	}
+
+	Convey("Given a document with nested objects", t, func() {
+		doc := parse(`{"title":"Goodbye!","author":{"givenName":"John","familyName":"Doe"},"tags":["example","sample"],"content":"This will be unchanged"}`)
+
+		Convey("When I apply the RFC 7396 sample patch", func() {
+			patch := parse(`{"title":"Hello!","phoneNumber":"+01-123-456-7890","author":{"familyName":null},"tags":["example"]}`)
+			result, changed := mergePatch(doc, patch)
+
+			Convey("It returns the patched document", func() {
+				So(result, ShouldResemble, parse(`{"title":"Hello!","author":{"givenName":"John"},"tags":["example"],"content":"This will be unchanged","phoneNumber":"+01-123-456-7890"}`))
+			})
+
+			Convey("It returns the changed paths", func() {
+				So(changed, ShouldResemble, []string{"author.familyName", "phoneNumber", "tags", "title"})
+			})
+		})
+
+		Convey("When I apply a patch that does not change anything", func() {
+			patch := parse(`{"title":"Goodbye!","author":{"givenName":"John"},"missing":null}`)
+			_, changed := mergePatch(doc, patch)
+
+			Convey("It returns no changed paths", func() {
+				So(changed, ShouldBeEmpty)
+			})
+		})
+
+		Convey("When I replace a scalar with an object", func() {
+			patch := parse(`{"content":{"text":"new","removed":null}}`)
+			result, changed := mergePatch(doc, patch)
+
+			Convey("It replaces the value, removing nulls", func() {
+				So(result.(map[string]interface{})["content"], ShouldResemble, parse(`{"text":"new"}`))
+			})
+
+			Convey("It returns the replaced field as changed", func() {
+				So(changed, ShouldResemble, []string{"content"})
+			})
+		})
+	})
+}
+
+func Test_jsonPatch(t *testing.T) {
+	parse := func(s string) interface{} {
+		var v interface{}
+		if err := decodeJSON([]byte(s), &v); err != nil {
+			panic(err)
+		}
+		return v
+	}
+	apply := func(doc string, ops string) (interface{}, []string, error) {
+		return jsonPatch(parse(doc), parse(ops).([]interface{}))
+	}
+
+	Convey("Given a document with nested objects and arrays", t, func() {
+		doc := `{"name":"Joe","address":{"city":"Paris","zip":"75001"},"tags":["a","b"],"age":30}`
+
+		Convey("When I apply add, remove and replace operations", func() {
+			result, changed, err := apply(doc, `[
+				{"op":"add","path":"/address/country","value":"FR"},
+				{"op":"remove","path":"/address/zip"},
+				{"op":"replace","path":"/name","value":"John"},
+				{"op":"add","path":"/tags/1","value":"x"},
+				{"op":"add","path":"/tags/-","value":"z"}
+			]`)
+
+			Convey("It returns the patched document", func() {
+				So(err, ShouldBeNil)
+				So(result, ShouldResemble, parse(`{"name":"John","address":{"city":"Paris","country":"FR"},"tags":["a","x","b","z"],"age":30}`))
+			})
+
+			Convey("It returns the touched paths, with arrays as a whole", func() {
+				So(changed, ShouldResemble, []string{"address.country", "address.zip", "name", "tags"})
+			})
+		})
+
+		Convey("When I apply move and copy operations", func() {
+			result, changed, err := apply(doc, `[
+				{"op":"move","from":"/address/city","path":"/city"},
+				{"op":"copy","from":"/tags","path":"/labels"}
+			]`)
+
+			Convey("It returns the patched document", func() {
+				So(err, ShouldBeNil)
+				So(result, ShouldResemble, parse(`{"name":"Joe","city":"Paris","address":{"zip":"75001"},"tags":["a","b"],"labels":["a","b"],"age":30}`))
+			})
+
+			Convey("It returns the source and destination paths", func() {
+				So(changed, ShouldResemble, []string{"address.city", "city", "labels"})
+			})
+		})
+
+		Convey("When I apply a successful test operation", func() {
+			_, changed, err := apply(doc, `[{"op":"test","path":"/age","value":30.0},{"op":"test","path":"/tags","value":["a","b"]}]`)
+
+			Convey("It does not return errors nor touched paths", func() {
+				So(err, ShouldBeNil)
+				So(changed, ShouldBeEmpty)
+			})
+		})
+
+		Convey("When I apply a failing test operation", func() {
+			_, _, err := apply(doc, `[{"op":"replace","path":"/name","value":"John"},{"op":"test","path":"/age","value":31}]`)
+
+			Convey("It returns errPatchTestFailed", func() {
+				So(errors.Is(err, errPatchTestFailed), ShouldBeTrue)
+			})
+		})
+
+		Convey("When I apply invalid operations", func() {
+			for _, ops := range []string{
+				`[{"op":"remove","path":"/missing"}]`,
+				`[{"op":"replace","path":"/tags/2","value":"c"}]`,
+				`[{"op":"add","path":"/tags/01","value":"c"}]`,
+				`[{"op":"add","path":"/name"}]`,
+				`[{"op":"move","from":"/address","path":"/address/city"}]`,
+				`[{"op":"invalid","path":"/name"}]`,
+				`[{"op":"add","path":"name","value":"x"}]`,
+				`["add"]`,
+			} {
+				_, _, err := apply(doc, ops)
+				So(err, ShouldNotBeNil)
+				So(errors.Is(err, errPatchTestFailed), ShouldBeFalse)
+			}
+		})
+
+		Convey("When I use escaped pointers", func() {
+			result, changed, err := apply(`{"a/b":1,"c~d":2}`, `[{"op":"remove","path":"/a~1b"},{"op":"replace","path":"/c~0d","value":3}]`)
+
+			Convey("It unescapes the reference tokens", func() {
+				So(err, ShouldBeNil)
+				So(result, ShouldResemble, parse(`{"c~d":3}`))
+				So(changed, ShouldResemble, []string{"a/b", "c~d"})
+			})
+		})
+	})
+}
@@ -0,0 +1,102 @@
+package rest
+
+import (
+	"encoding/json"
+	"net/http"
+	"sort"
+	"sync/atomic"
+)
+
+/*
+Problem is an error response formatted as specified by RFC 7807 (Problem Details for HTTP APIs). When the
+ProblemDetails mode is enabled, all errors returned by the Controller are sent as Problems, with the
+application/problem+json content type
+*/
+type Problem struct {
+	// URI that identifies the problem type. Defaults to "about:blank"
+	Type string `json:"type"`
+
+	// Short summary of the problem type. Defaults to the http status text
+	Title string `json:"title"`
+
+	// Http status code
+	Status int `json:"status"`
+
+	// Explanation specific to this occurrence of the problem
+	Detail string `json:"detail,omitempty"`
+
+	// URI that identifies this occurrence of the problem. Defaults to the request path
+	Instance string `json:"instance,omitempty"`
+
+	// Extension with the invalid fields, when the repository returned a ValidationError
+	InvalidParams []InvalidParam `json:"invalid-params,omitempty"`
+
+	// Extension with the ID of an internal error, to match it with the logs. See ErrorSanitizer
+	CorrelationID string `json:"correlation-id,omitempty"`
+}
+
+// InvalidParam is an entry of the invalid-params extension of a Problem
+type InvalidParam struct {
+	// Name of the invalid field
+	Name string `json:"name"`
+
+	// Why the field is invalid
+	Reason string `json:"reason"`
+}
+
+const problemContentType = "application/problem+json"
+
+var problemDetails int32
+
+/*
+EnableProblemDetails sets the default error format for the controllers created by the handler functions. When
+enabled, errors are sent as RFC 7807 Problems, instead of the default {"error": "message"} object. See
+Controller.ProblemDetails
+*/
+func EnableProblemDetails(enabled bool) {
+	var v int32
+	if enabled {
+		v = 1
+	}
+	atomic.StoreInt32(&problemDetails, v)
+}
+
+func problemDetailsEnabled() bool {
+	return atomic.LoadInt32(&problemDetails) == 1
+}
+
+// respondWithProblem sends the problem as application/problem+json, filling in the default values
+func (c *Controller) respondWithProblem(w http.ResponseWriter, r *http.Request, problem Problem) {
+	if problem.Type == "" {
+		problem.Type = "about:blank"
+	}
+	if problem.Title == "" {
+		problem.Title = http.StatusText(problem.Status)
+	}
+	if problem.Instance == "" {
+		problem.Instance = r.URL.Path
+	}
+	body, err := json.Marshal(problem)
+	if err != nil {
+		c.errorf(r, "Rendering problem %#v: %v", problem, err)
+		RespondWithError(w, http.StatusInternalServerError, http.StatusText(http.StatusInternalServerError))
+		return
+	}
+	writeResponse(w, problem.Status, problemContentType, body)
+}
+
+// respondWithValidationError sends the errors of a ValidationError with a 400 http status
+func (c *Controller) respondWithValidationError(w http.ResponseWriter, r *http.Request, e *ValidationError) {
+	if !c.ProblemDetails {
+		c.respond(w, r, http.StatusBadRequest, e)
+		return
+	}
+	problem := Problem{Status: http.StatusBadRequest, Detail: "Validation failed"}
+	for name, reason := range e.Errors {
+		problem.InvalidParams = append(problem.InvalidParams, InvalidParam{Name: name, Reason: reason})
+	}
+	sort.Slice(problem.InvalidParams, func(i, j int) bool {
+		return problem.InvalidParams[i].Name < problem.InvalidParams[j].Name
+	})
+	c.respondWithProblem(w, r, problem)
+}
@@ -0,0 +1,75 @@
+package rest_test
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/deluan/rest"
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestController_ProblemDetails(t *testing.T) {
+	Convey("Given the ProblemDetails mode is enabled", t, func() {
+		rest.EnableProblemDetails(true)
+		defer rest.EnableProblemDetails(false)
+
+		Convey("When I call Get for a missing record", func() {
+			handler, _ := createPersistableHandler(rest.Get)
+			req, res := createRequestResponse("GET", "/sample?:id=1", nil)
+			handler(res, req)
+
+			Convey("It returns a problem", func() {
+				So(res.Code, ShouldEqual, 404)
+				So(res.Header().Get("Content-Type"), ShouldEqual, "application/problem+json")
+				So(res.Body.String(), ShouldEqual,
+					`{"type":"about:blank","title":"Not Found","status":404,"detail":"sample(id:1) not found","instance":"/sample"}`)
+			})
+		})
+
+		Convey("When the repository returns a ValidationError", func() {
+			handler, repo := createPersistableHandler(rest.Post)
+			repo.Error = &rest.ValidationError{Errors: map[string]string{
+				"name": "required",
+				"age":  "must be positive",
+			}}
+			req, res := createRequestResponse("POST", "/sample", aRecordReader("0", "", -1))
+			handler(res, req)
+
+			Convey("It returns the errors in the invalid-params extension", func() {
+				So(res.Code, ShouldEqual, 400)
+				So(res.Header().Get("Content-Type"), ShouldEqual, "application/problem+json")
+				var problem rest.Problem
+				_ = json.Unmarshal(res.Body.Bytes(), &problem)
+				So(problem.Title, ShouldEqual, "Bad Request")
+				So(problem.InvalidParams, ShouldResemble, []rest.InvalidParam{
+					{Name: "age", Reason: "must be positive"},
+					{Name: "name", Reason: "required"},
+				})
+			})
+		})
+
+		Convey("When I call GetAll accepting an unsupported media type", func() {
+			handler, _ := createPersistableHandler(rest.GetAll)
+			req, res := createRequestResponse("GET", "/sample", nil)
+			req.Header.Set("Accept", "text/html")
+			handler(res, req)
+
+			Convey("It returns a problem", func() {
+				So(res.Code, ShouldEqual, 406)
+				So(res.Header().Get("Content-Type"), ShouldEqual, "application/problem+json")
+			})
+		})
+	})
+
+	Convey("Given the ProblemDetails mode is disabled", t, func() {
+		handler, _ := createPersistableHandler(rest.Get)
+		req, res := createRequestResponse("GET", "/sample?:id=1", nil)
+		handler(res, req)
+
+		Convey("It returns the error message", func() {
+			So(res.Code, ShouldEqual, 404)
+			So(res.Header().Get("Content-Type"), ShouldEqual, "application/json")
+			So(res.Body.String(), ShouldEqual, `{"error":"sample(id:1) not found"}`)
+		})
+	})
+}
@@ -1,8 +1,10 @@
 package rest
 
 import (
+	"bytes"
 	"encoding/json"
 	"net/http"
+	"strings"
 )
 
 // RespondWithError returns an error message formatted as a JSON object, and sets the http status to code
@@ -16,9 +18,74 @@ func RespondWithJSON(w http.ResponseWriter, code int, payload interface{}) error
 	if err != nil {
 		return err
 	}
+	writeResponse(w, code, "application/json", response)
+	return nil
+}
 
-	w.Header().Set("Content-Type", "application/json")
+func writeResponse(w http.ResponseWriter, code int, contentType string, response []byte) {
+	w.Header().Set("Content-Type", contentType)
 	w.WriteHeader(code)
 	w.Write(response)
-	return nil
+}
+
+// respond sends the payload using the renderer negotiated with the client
+func (c *Controller) respond(w http.ResponseWriter, r *http.Request, code int, payload interface{}) {
+	body, renderer, err := c.render(r, payload)
+	if err != nil {
+		c.respondWithInternalError(w, r, http.StatusInternalServerError, "Rendering "+c.repository().EntityName(), err)
+		return
+	}
+	writeResponse(w, code, renderer.ContentType(), body)
+}
+
+// respondWithError sends an error message using the renderer negotiated with the client, or as a Problem if the
+// ProblemDetails mode is enabled
+func (c *Controller) respondWithError(w http.ResponseWriter, r *http.Request, code int, message string) {
+	if c.ProblemDetails {
+		c.respondWithProblem(w, r, Problem{Status: code, Detail: message})
+		return
+	}
+	c.respond(w, r, code, map[string]string{"error": message})
+}
+
+// render renders the payload with the renderer negotiated with the client. If there is no acceptable renderer, or if
+// it fails to render the payload, it falls back to JSON
+func (c *Controller) render(r *http.Request, payload interface{}) ([]byte, Renderer, error) {
+	var buf bytes.Buffer
+	if renderer, ok := c.negotiate(r); ok {
+		err := renderer.Render(&buf, payload)
+		if err == nil {
+			return buf.Bytes(), renderer, nil
+		}
+		c.warnf(r, "Rendering %s as %s: %v", c.repository().EntityName(), renderer.ContentType(), err)
+		buf.Reset()
+	}
+	renderer := JSONRenderer{}
+	err := renderer.Render(&buf, payload)
+	return buf.Bytes(), renderer, err
+}
+
+// checkAcceptable verifies that the response can be rendered in a format accepted by the client. If not, it sends a
+// 406 - Not Acceptable response and returns false
+func (c *Controller) checkAcceptable(w http.ResponseWriter, r *http.Request) bool {
+	if _, ok := c.negotiate(r); ok {
+		addVary(w.Header(), "Accept")
+		return true
+	}
+	c.warnf(r, "Not acceptable: Accept=%q, _format=%q", r.Header.Get("Accept"), r.URL.Query().Get("_format"))
+	c.respondWithError(w, r, http.StatusNotAcceptable, "406 Not Acceptable")
+	return false
+}
+
+// addVary adds the field to the Vary header, if it is not already there. Handlers like Put and Patch call Get to send
+// their response, so the header may have been added before
+func addVary(header http.Header, field string) {
+	for _, value := range header["Vary"] {
+		for _, f := range strings.Split(value, ",") {
+			if strings.EqualFold(strings.TrimSpace(f), field) {
+				return
+			}
+		}
+	}
+	header.Add("Vary", field)
 }
@@ -0,0 +1,523 @@
+package rest
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/csv"
+	"encoding/json"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"mime"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"unicode"
+)
+
+/*
+Renderer converts the payloads sent by the controller to a specific media type. Renderers are selected by the _format
+query param (using the name they were registered with) or by the Accept header (using their ContentType). Custom
+renderers can be added with RegisterRenderer.
+*/
+type Renderer interface {
+	// Returns the media type produced by this renderer. Ex: "application/json"
+	ContentType() string
+
+	// Writes the payload to w
+	Render(w io.Writer, payload interface{}) error
+}
+
+type registeredRenderer struct {
+	format   string
+	renderer Renderer
+}
+
+var (
+	renderersMu sync.RWMutex
+	renderers   = []registeredRenderer{
+		{"json", JSONRenderer{}},
+		{"csv", CSVRenderer{}},
+		{"xml", XMLRenderer{}},
+		{"msgpack", MsgPackRenderer{}},
+		{"ndjson", NDJSONRenderer{}},
+	}
+)
+
+/*
+RegisterRenderer adds a renderer to the registry, under the specified format name. If a renderer is already
+registered with this name, it is replaced. When more than one renderer matches the Accept header with the same
+quality, the first registered is used. The JSON renderer is the default, used when the client does not specify a
+format
+*/
+func RegisterRenderer(format string, renderer Renderer) {
+	renderersMu.Lock()
+	defer renderersMu.Unlock()
+	for i, r := range renderers {
+		if r.format == format {
+			renderers[i].renderer = renderer
+			return
+		}
+	}
+	renderers = append(renderers, registeredRenderer{format, renderer})
+}
+
+// negotiate selects the renderer for the request, among the registered renderers and the ones added to the controller
+func (c *Controller) negotiate(r *http.Request) (Renderer, bool) {
+	return negotiate(r, c.renderers)
+}
+
+// negotiate selects the renderer for the request, returning false if none of the registered renderers (or the extra
+// ones, that replace the registered renderers with the same format) is acceptable
+func negotiate(r *http.Request, extra []registeredRenderer) (Renderer, bool) {
+	renderersMu.RLock()
+	defer renderersMu.RUnlock()
+	renderers := mergeRenderers(renderers, extra)
+	if format := r.URL.Query().Get("_format"); format != "" {
+		for _, rr := range renderers {
+			if rr.format == format {
+				return rr.renderer, true
+			}
+		}
+		return nil, false
+	}
+	accept := r.Header.Get("Accept")
+	if accept == "" {
+		return renderers[0].renderer, true
+	}
+	ranges, excluded := parseAccept(accept)
+	for _, mediaRange := range ranges {
+		wildcard := strings.HasSuffix(mediaRange, "/*")
+		for _, rr := range renderers {
+			if rendererMatches(mediaRange, rr.renderer) && !(wildcard && rendererExcluded(excluded, rr.renderer)) {
+				return rr.renderer, true
+			}
+		}
+	}
+	return nil, false
+}
+
+// rendererExcluded returns true if the renderer matches any of the media ranges excluded by the client (with q=0)
+func rendererExcluded(excluded []string, renderer Renderer) bool {
+	for _, mediaRange := range excluded {
+		if rendererMatches(mediaRange, renderer) {
+			return true
+		}
+	}
+	return false
+}
+
+// aliased is implemented by renderers that also accept other media types, besides their ContentType
+type aliased interface {
+	aliases() []string
+}
+
+func rendererMatches(mediaRange string, renderer Renderer) bool {
+	if mediaRangeMatches(mediaRange, renderer.ContentType()) {
+		return true
+	}
+	if a, ok := renderer.(aliased); ok {
+		for _, alias := range a.aliases() {
+			if mediaRangeMatches(mediaRange, alias) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func mergeRenderers(registered, extra []registeredRenderer) []registeredRenderer {
+	if len(extra) == 0 {
+		return registered
+	}
+	merged := append([]registeredRenderer(nil), registered...)
+	for _, e := range extra {
+		replaced := false
+		for i, rr := range merged {
+			if rr.format == e.format {
+				merged[i].renderer = e.renderer
+				replaced = true
+			}
+		}
+		if !replaced {
+			merged = append(merged, e)
+		}
+	}
+	return merged
+}
+
+// parseAccept returns the acceptable media ranges from an Accept header, sorted by quality, and the ones excluded with
+// q=0, that must not be selected by the wildcard ranges
+func parseAccept(accept string) (ranges []string, excluded []string) {
+	type mediaRange struct {
+		value string
+		q     float64
+	}
+	var acceptable []mediaRange
+	for _, part := range strings.Split(accept, ",") {
+		mediaType, params, err := mime.ParseMediaType(strings.TrimSpace(part))
+		if err != nil {
+			continue
+		}
+		q := 1.0
+		if v, ok := params["q"]; ok {
+			if q, err = strconv.ParseFloat(v, 64); err != nil {
+				continue
+			}
+		}
+		if q > 0 {
+			acceptable = append(acceptable, mediaRange{mediaType, q})
+		} else {
+			excluded = append(excluded, mediaType)
+		}
+	}
+	sort.SliceStable(acceptable, func(i, j int) bool { return acceptable[i].q > acceptable[j].q })
+	ranges = make([]string, len(acceptable))
+	for i, r := range acceptable {
+		ranges[i] = r.value
+	}
+	return ranges, excluded
+}
+
+func mediaRangeMatches(mediaRange string, contentType string) bool {
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		return false
+	}
+	if mediaRange == "*/*" || mediaRange == mediaType {
+		return true
+	}
+	return strings.HasSuffix(mediaRange, "/*") && strings.HasPrefix(mediaType, strings.TrimSuffix(mediaRange, "*"))
+}
+
+// JSONRenderer renders payloads as JSON
+type JSONRenderer struct{}
+
+// ContentType returns application/json
+func (JSONRenderer) ContentType() string { return "application/json" }
+
+// Render writes the payload as JSON
+func (JSONRenderer) Render(w io.Writer, payload interface{}) error {
+	buf, err := json.Marshal(payload)
+	if err != nil {
+		return err
+	}
+	_, err = w.Write(buf)
+	return err
+}
+
+/*
+CSVRenderer renders collections as CSV, with one line per entity and a header with the field names. Single entities are
+rendered as a collection of one. Nested objects and arrays are rendered as JSON. Field names and values are the same
+as in the JSON representation of the entities
+*/
+type CSVRenderer struct{}
+
+// ContentType returns text/csv
+func (CSVRenderer) ContentType() string { return "text/csv" }
+
+// Render writes the payload as CSV
+func (CSVRenderer) Render(w io.Writer, payload interface{}) error {
+	doc, err := toOrderedDocument(payload)
+	if err != nil {
+		return err
+	}
+	rows, ok := doc.([]interface{})
+	if !ok {
+		rows = []interface{}{doc}
+	}
+	var header []string
+	seen := map[string]bool{}
+	for _, row := range rows {
+		obj, ok := row.(*orderedObject)
+		if !ok {
+			return errors.New("CSV can only render objects or collections of objects")
+		}
+		for _, k := range obj.keys {
+			if !seen[k] {
+				seen[k] = true
+				header = append(header, k)
+			}
+		}
+	}
+	if len(header) == 0 {
+		return nil
+	}
+	writer := csv.NewWriter(w)
+	_ = writer.Write(header)
+	for _, row := range rows {
+		obj := row.(*orderedObject)
+		record := make([]string, len(header))
+		for i, k := range header {
+			if record[i], err = csvValue(obj.values[k]); err != nil {
+				return err
+			}
+		}
+		_ = writer.Write(record)
+	}
+	writer.Flush()
+	return writer.Error()
+}
+
+func csvValue(value interface{}) (string, error) {
+	switch v := value.(type) {
+	case nil:
+		return "", nil
+	case string:
+		return v, nil
+	case json.Number:
+		return v.String(), nil
+	case bool:
+		return strconv.FormatBool(v), nil
+	}
+	buf, err := json.Marshal(value)
+	return string(buf), err
+}
+
+/*
+XMLRenderer renders payloads as XML, inside a <response> root element. Object fields are rendered as elements named
+after the fields (or as <field name="..."> elements, if the names are not valid XML names), and array elements are
+rendered as <item> elements. Field names and values are the same as in the JSON representation of the payload
+*/
+type XMLRenderer struct{}
+
+// ContentType returns application/xml
+func (XMLRenderer) ContentType() string { return "application/xml" }
+
+// Render writes the payload as XML
+func (XMLRenderer) Render(w io.Writer, payload interface{}) error {
+	doc, err := toOrderedDocument(payload)
+	if err != nil {
+		return err
+	}
+	if _, err := io.WriteString(w, xml.Header); err != nil {
+		return err
+	}
+	encoder := xml.NewEncoder(w)
+	if err := writeXMLElement(encoder, "response", doc); err != nil {
+		return err
+	}
+	return encoder.Flush()
+}
+
+func writeXMLElement(encoder *xml.Encoder, name string, value interface{}) error {
+	start := xml.StartElement{Name: xml.Name{Local: name}}
+	if !isXMLName(name) {
+		start = xml.StartElement{
+			Name: xml.Name{Local: "field"},
+			Attr: []xml.Attr{{Name: xml.Name{Local: "name"}, Value: name}},
+		}
+	}
+	if err := encoder.EncodeToken(start); err != nil {
+		return err
+	}
+	switch v := value.(type) {
+	case *orderedObject:
+		for _, k := range v.keys {
+			if err := writeXMLElement(encoder, k, v.values[k]); err != nil {
+				return err
+			}
+		}
+	case []interface{}:
+		for _, e := range v {
+			if err := writeXMLElement(encoder, "item", e); err != nil {
+				return err
+			}
+		}
+	case nil:
+	default:
+		if err := encoder.EncodeToken(xml.CharData(fmt.Sprint(v))); err != nil {
+			return err
+		}
+	}
+	return encoder.EncodeToken(start.End())
+}
+
+// isXMLName reports whether name can be used as an element name. Names with colons are rejected, as they would be
+// read as namespace prefixes
+func isXMLName(name string) bool {
+	if name == "" {
+		return false
+	}
+	for i, r := range name {
+		switch {
+		case unicode.IsLetter(r) || r == '_':
+		case i > 0 && (unicode.IsDigit(r) || r == '-' || r == '.'):
+		default:
+			return false
+		}
+	}
+	return true
+}
+
+/*
+MsgPackRenderer renders payloads as MessagePack. Field names and values are the same as in the JSON representation of
+the payload
+*/
+type MsgPackRenderer struct{}
+
+// ContentType returns application/msgpack
+func (MsgPackRenderer) ContentType() string { return "application/msgpack" }
+
+// aliases returns the unofficial application/x-msgpack media type, still sent by many clients
+func (MsgPackRenderer) aliases() []string { return []string{"application/x-msgpack"} }
+
+// Render writes the payload as MessagePack
+func (MsgPackRenderer) Render(w io.Writer, payload interface{}) error {
+	doc, err := toOrderedDocument(payload)
+	if err != nil {
+		return err
+	}
+	var buf bytes.Buffer
+	writeMsgPack(&buf, doc)
+	_, err = w.Write(buf.Bytes())
+	return err
+}
+
+func writeMsgPack(buf *bytes.Buffer, value interface{}) {
+	switch v := value.(type) {
+	case nil:
+		buf.WriteByte(0xc0)
+	case bool:
+		if v {
+			buf.WriteByte(0xc3)
+		} else {
+			buf.WriteByte(0xc2)
+		}
+	case string:
+		writeMsgPackHeader(buf, len(v), 0xa0, 32, 0xd9, 0xda, 0xdb)
+		buf.WriteString(v)
+	case json.Number:
+		if i, err := v.Int64(); err == nil {
+			writeMsgPackInt(buf, i)
+			return
+		}
+		f, _ := v.Float64()
+		buf.WriteByte(0xcb)
+		_ = binary.Write(buf, binary.BigEndian, math.Float64bits(f))
+	case []interface{}:
+		writeMsgPackHeader(buf, len(v), 0x90, 16, 0, 0xdc, 0xdd)
+		for _, e := range v {
+			writeMsgPack(buf, e)
+		}
+	case *orderedObject:
+		writeMsgPackHeader(buf, len(v.keys), 0x80, 16, 0, 0xde, 0xdf)
+		for _, k := range v.keys {
+			writeMsgPack(buf, k)
+			writeMsgPack(buf, v.values[k])
+		}
+	}
+}
+
+// writeMsgPackHeader writes the type and length of a string, array or map, using the smallest format possible.
+// A zero code means the format is not available for the type
+func writeMsgPackHeader(buf *bytes.Buffer, length int, fixCode byte, fixLimit int, code8, code16, code32 byte) {
+	switch {
+	case length < fixLimit:
+		buf.WriteByte(fixCode | byte(length))
+	case code8 != 0 && length <= math.MaxUint8:
+		buf.WriteByte(code8)
+		buf.WriteByte(byte(length))
+	case length <= math.MaxUint16:
+		buf.WriteByte(code16)
+		_ = binary.Write(buf, binary.BigEndian, uint16(length))
+	default:
+		buf.WriteByte(code32)
+		_ = binary.Write(buf, binary.BigEndian, uint32(length))
+	}
+}
+
+func writeMsgPackInt(buf *bytes.Buffer, i int64) {
+	switch {
+	case i >= 0 && i < 128:
+		buf.WriteByte(byte(i))
+	case i < 0 && i >= -32:
+		buf.WriteByte(byte(int8(i)))
+	default:
+		buf.WriteByte(0xd3)
+		_ = binary.Write(buf, binary.BigEndian, i)
+	}
+}
+
+// orderedObject is a JSON object that keeps the order of its fields
+type orderedObject struct {
+	keys   []string
+	values map[string]interface{}
+}
+
+// MarshalJSON writes the object with the fields in their original order
+func (o *orderedObject) MarshalJSON() ([]byte, error) {
+	var buf bytes.Buffer
+	buf.WriteByte('{')
+	for i, k := range o.keys {
+		if i > 0 {
+			buf.WriteByte(',')
+		}
+		key, _ := json.Marshal(k)
+		value, err := json.Marshal(o.values[k])
+		if err != nil {
+			return nil, err
+		}
+		buf.Write(key)
+		buf.WriteByte(':')
+		buf.Write(value)
+	}
+	buf.WriteByte('}')
+	return buf.Bytes(), nil
+}
+
+// toOrderedDocument converts the payload to its generic JSON representation, keeping the order of the fields. Objects
+// are represented as *orderedObject, arrays as []interface{} and numbers as json.Number
+func toOrderedDocument(payload interface{}) (interface{}, error) {
+	buf, err := json.Marshal(payload)
+	if err != nil {
+		return nil, err
+	}
+	decoder := json.NewDecoder(bytes.NewReader(buf))
+	decoder.UseNumber()
+	return decodeOrdered(decoder)
+}
+
+func decodeOrdered(decoder *json.Decoder) (interface{}, error) {
+	token, err := decoder.Token()
+	if err != nil {
+		return nil, err
+	}
+	switch token {
+	case json.Delim('{'):
+		obj := &orderedObject{values: map[string]interface{}{}}
+		for decoder.More() {
+			key, err := decoder.Token()
+			if err != nil {
+				return nil, err
+			}
+			value, err := decodeOrdered(decoder)
+			if err != nil {
+				return nil, err
+			}
+			k := key.(string)
+			if _, exists := obj.values[k]; !exists {
+				obj.keys = append(obj.keys, k)
+			}
+			obj.values[k] = value
+		}
+		_, err = decoder.Token()
+		return obj, err
+	case json.Delim('['):
+		arr := []interface{}{}
+		for decoder.More() {
+			value, err := decodeOrdered(decoder)
+			if err != nil {
+				return nil, err
+			}
+			arr = append(arr, value)
+		}
+		_, err = decoder.Token()
+		return arr, err
+	}
+	return token, nil
+}
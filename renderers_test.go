@@ -0,0 +1,157 @@
+package rest
+
+import (
+	"bytes"
+	"io"
+	"net/http/httptest"
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+type renderModel struct {
+	ID   string   `json:"id"`
+	Name string   `json:"name"`
+	Age  int      `json:"age"`
+	Tags []string `json:"tags,omitempty"`
+}
+
+type customRenderer struct{}
+
+func (customRenderer) ContentType() string { return "text/plain" }
+
+func (customRenderer) Render(w io.Writer, payload interface{}) error {
+	_, err := io.WriteString(w, "custom")
+	return err
+}
+
+func render(renderer Renderer, payload interface{}) string {
+	var buf bytes.Buffer
+	if err := renderer.Render(&buf, payload); err != nil {
+		panic(err)
+	}
+	return buf.String()
+}
+
+func TestRenderers(t *testing.T) {
+	collection := []renderModel{{ID: "1", Name: "Joe, Jr.", Age: 30}, {ID: "2", Name: "Cecilia", Age: 22, Tags: []string{"a"}}}
+
+	Convey("Given the CSV renderer", t, func() {
+		Convey("It renders a collection, keeping the field order", func() {
+			So(render(CSVRenderer{}, collection), ShouldEqual,
+				"id,name,age,tags\n1,\"Joe, Jr.\",30,\n2,Cecilia,22,\"[\"\"a\"\"]\"\n")
+		})
+
+		Convey("It renders a single entity as a collection of one", func() {
+			So(render(CSVRenderer{}, collection[0]), ShouldEqual, "id,name,age\n1,\"Joe, Jr.\",30\n")
+		})
+
+		Convey("It renders an empty collection as an empty document", func() {
+			So(render(CSVRenderer{}, []renderModel{}), ShouldBeEmpty)
+		})
+
+		Convey("It fails to render a collection of scalars", func() {
+			So(CSVRenderer{}.Render(&bytes.Buffer{}, []int{1, 2}), ShouldNotBeNil)
+		})
+	})
+
+	Convey("Given the XML renderer", t, func() {
+		Convey("It renders a collection", func() {
+			So(render(XMLRenderer{}, collection), ShouldEqual, `<?xml version="1.0" encoding="UTF-8"?>`+"\n"+
+				`<response><item><id>1</id><name>Joe, Jr.</name><age>30</age></item>`+
+				`<item><id>2</id><name>Cecilia</name><age>22</age><tags><item>a</item></tags></item></response>`)
+		})
+
+		Convey("It escapes special characters", func() {
+			So(render(XMLRenderer{}, map[string]string{"error": "<a & b>"}), ShouldEndWith,
+				`<response><error>&lt;a &amp; b&gt;</error></response>`)
+		})
+
+		Convey("It renders fields with invalid XML names as field elements", func() {
+			So(render(XMLRenderer{}, map[string]interface{}{"first name": 1, "2x": "<a>", "ok": true}), ShouldEndWith,
+				`<response><field name="2x">&lt;a&gt;</field><field name="first name">1</field><ok>true</ok></response>`)
+		})
+	})
+
+	Convey("Given the NDJSON renderer", t, func() {
+		Convey("It renders a collection with one entity per line", func() {
+			So(render(NDJSONRenderer{}, collection), ShouldEqual,
+				`{"id":"1","name":"Joe, Jr.","age":30}`+"\n"+`{"id":"2","name":"Cecilia","age":22,"tags":["a"]}`+"\n")
+		})
+
+		Convey("It renders a single entity in one line", func() {
+			So(render(NDJSONRenderer{}, map[string]string{"error": "not found"}), ShouldEqual, `{"error":"not found"}`+"\n")
+		})
+	})
+
+	Convey("Given the MessagePack renderer", t, func() {
+		Convey("It renders a map", func() {
+			So([]byte(render(MsgPackRenderer{}, collection[1])), ShouldResemble, []byte{
+				0x84,
+				0xa2, 'i', 'd', 0xa1, '2',
+				0xa4, 'n', 'a', 'm', 'e', 0xa7, 'C', 'e', 'c', 'i', 'l', 'i', 'a',
+				0xa3, 'a', 'g', 'e', 22,
+				0xa4, 't', 'a', 'g', 's', 0x91, 0xa1, 'a',
+			})
+		})
+
+		Convey("It renders other scalars", func() {
+			So([]byte(render(MsgPackRenderer{}, []interface{}{nil, true, false, -1, -100, 1.5})), ShouldResemble, []byte{
+				0x96, 0xc0, 0xc3, 0xc2, 0xff,
+				0xd3, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0x9c,
+				0xcb, 0x3f, 0xf8, 0, 0, 0, 0, 0, 0,
+			})
+		})
+	})
+}
+
+func Test_negotiate(t *testing.T) {
+	negotiated := func(accept string, target string) string {
+		r := httptest.NewRequest("GET", target, nil)
+		if accept != "" {
+			r.Header.Set("Accept", accept)
+		}
+		renderer, ok := negotiate(r, nil)
+		if !ok {
+			return ""
+		}
+		return renderer.ContentType()
+	}
+
+	Convey("Given no Accept header", t, func() {
+		So(negotiated("", "/thing"), ShouldEqual, "application/json")
+	})
+
+	Convey("Given an Accept header", t, func() {
+		So(negotiated("*/*", "/thing"), ShouldEqual, "application/json")
+		So(negotiated("text/csv", "/thing"), ShouldEqual, "text/csv")
+		So(negotiated("text/*", "/thing"), ShouldEqual, "text/csv")
+		So(negotiated("application/xml;q=0.5, application/msgpack", "/thing"), ShouldEqual, "application/msgpack")
+		So(negotiated("text/html, application/xml;q=0.9, */*;q=0.8", "/thing"), ShouldEqual, "application/xml")
+		So(negotiated("application/x-ndjson", "/thing"), ShouldEqual, "application/x-ndjson")
+		So(negotiated("application/x-msgpack", "/thing"), ShouldEqual, "application/msgpack")
+		So(negotiated("text/html", "/thing"), ShouldBeEmpty)
+	})
+
+	Convey("Given an Accept header with excluded media types", t, func() {
+		So(negotiated("application/json;q=0, */*", "/thing"), ShouldEqual, "text/csv")
+		So(negotiated("application/json;q=0, text/csv;q=0, */*", "/thing"), ShouldEqual, "application/xml")
+		So(negotiated("application/*;q=0, */*", "/thing"), ShouldEqual, "text/csv")
+		So(negotiated("text/*;q=0, text/csv", "/thing"), ShouldEqual, "text/csv")
+		So(negotiated("application/json;q=0, application/json", "/thing"), ShouldEqual, "application/json")
+		So(negotiated("*/*;q=0", "/thing"), ShouldBeEmpty)
+	})
+
+	Convey("Given a _format param", t, func() {
+		So(negotiated("application/json", "/thing?_format=csv"), ShouldEqual, "text/csv")
+		So(negotiated("", "/thing?_format=yaml"), ShouldBeEmpty)
+	})
+
+	Convey("Given a custom renderer", t, func() {
+		defer renderersSnapshot()()
+		RegisterRenderer("text", customRenderer{})
+
+		So(negotiated("text/plain", "/thing"), ShouldEqual, "text/plain")
+		So(negotiated("", "/thing?_format=text"), ShouldEqual, "text/plain")
+	})
+}
@@ -2,6 +2,7 @@ package rest
 
 import (
 	"context"
+	"time"
 )
 
 /*
@@ -9,12 +10,17 @@ QueryOptions are optional query parameters that can be received by Count and Rea
 sorting and filtering.
 */
 type QueryOptions struct {
-	// Comma separated list of fields to sort the data
+	// Comma separated list of fields to sort the data, as received in the _sort param
 	Sort string
 
-	// Possible values: asc (default), desc
+	// Comma separated list of sort directions, as received in the _order param. Possible values: asc (default), desc
 	Order string
 
+	// Sort and Order parsed into an ordered list of fields. When not empty, a SortField for the controller's tie-breaker
+	// field (IDField by default, see WithSortTieBreaker) is always appended (if not already present), so pagination
+	// is stable
+	SortFields []SortField
+
 	// Max records to return. Used for pagination
 	Max int
 
@@ -26,6 +32,63 @@ type QueryOptions struct {
 	// How the values of the filters are applied to the fields is implementation dependent
 	// (you can implement substring, exact match, etc..)
 	Filters map[string]interface{}
+
+	// Same filters as above, parsed into a list of Filter structs. JSON Server operator suffixes (_gte, _lte, _ne,
+	// _like, _in and _nin) are removed from the field names and converted to the corresponding FilterOperator.
+	// Eg.: age_gte=30&name=john -> [{"age", "gte", ["30"]}, {"name", "eq", ["john"]}]
+	FilterList []Filter
+
+	// Full-text search term, received in the q param. It is not included in the filters above.
+	// Which fields are searched is implementation dependent
+	Search string
+}
+
+// IDField is the default name of the field used as a sorting tie-breaker. Repositories with a different key should
+// map it to their own key, or configure the controller with WithSortTieBreaker. See QueryOptions.SortFields
+const IDField = "id"
+
+// SortField is one of the fields used to sort the data
+type SortField struct {
+	// Field name
+	Field string
+
+	// True if the sort direction is descending
+	Desc bool
+}
+
+// FilterOperator specifies how a Filter's values should be compared to the field
+type FilterOperator string
+
+// Possible FilterOperators. How the operators are applied is implementation dependent
+const (
+	OpEq   FilterOperator = "eq"   // field equals one of the values (default)
+	OpNe   FilterOperator = "ne"   // field does not equal the value
+	OpGte  FilterOperator = "gte"  // field is greater than or equal to the value
+	OpLte  FilterOperator = "lte"  // field is less than or equal to the value
+	OpLike FilterOperator = "like" // field matches the value (usually a substring or regexp match)
+	OpIn   FilterOperator = "in"   // field equals one of the values
+	OpNin  FilterOperator = "nin"  // field does not equal any of the values
+)
+
+// Filter is a single filter criteria, parsed from the query params
+type Filter struct {
+	// Field name, without the operator suffix
+	Field string
+
+	// Operator to apply
+	Operator FilterOperator
+
+	// Values to compare with the field. For OpIn and OpNin, comma separated values are split into multiple values.
+	// Repeated params also produce multiple values
+	Values []string
+}
+
+// Value returns the first value of the filter, or an empty string if there are none
+func (f Filter) Value() string {
+	if len(f.Values) == 0 {
+		return ""
+	}
+	return f.Values[0]
 }
 
 /*
@@ -71,3 +134,41 @@ type Persistable interface {
 	// Delete the entity identified by id
 	Delete(id string) error
 }
+
+/*
+Versioned can be implemented by entities to enable optimistic concurrency control. A hash of the version and of the
+content type of the response is sent to the client in the ETag header of GET responses, and PUT, PATCH and DELETE
+requests with an If-Match header are rejected with 412 - Precondition Failed if it does not match the current version
+of the entity, in the format negotiated for the request. As the check and the update are not atomic, Persistable
+implementations should also verify the version when updating, returning ErrConflict if it changed. If the entity does
+not implement this interface, a hash of its rendered representation is used as the ETag
+*/
+type Versioned interface {
+	// Returns a token that changes every time the entity is modified. Ex: a revision number or a hash
+	Version() string
+}
+
+/*
+Timestamped can be implemented by entities to send their modification time in the Last-Modified header of GET
+responses, enabling conditional requests with If-Modified-Since. For collections, the header is only sent if all
+entities implement this interface
+*/
+type Timestamped interface {
+	// Returns the time of the last modification of the entity
+	LastModified() time.Time
+}
+
+/*
+BulkPersistable can be implemented by repositories in addition to the Persistable interface, to handle the BulkPost and
+BulkDelete methods more efficiently (ex: in a single transaction). If this interface is not implemented, these methods
+call Save and Delete for each entity. See also BulkPersistableV2.
+*/
+type BulkPersistable interface {
+	// Adds the entities to the repository. Returns the newly created ids and the errors (nil if successful) for each
+	// entity, in the same order as received
+	SaveAll(entities []interface{}) ([]string, []error)
+
+	// Deletes the entities identified by ids. Returns the errors (nil if successful) for each id, in the same order
+	// as received
+	DeleteAll(ids []string) []error
+}
@@ -0,0 +1,260 @@
+package rest
+
+import "context"
+
+/*
+RepositoryV2 is a context-aware version of the Repository interface: the context of the current request is passed to
+every method, so implementations don't need to keep it, and cancellations reach the data layer. Controllers prefer
+it over Repository (see Controller.RepositoryV2). To use it with the handler functions, wrap it with FromV2.
+RepositoryV2 implementations can also implement the context-aware versions of the optional interfaces:
+CursorPaginatedV2, StreamableV2 and BulkPersistableV2.
+*/
+type RepositoryV2 interface {
+	// Returns the number of entities that matches the criteria specified by the options
+	Count(ctx context.Context, options ...QueryOptions) (int64, error)
+
+	// Returns the entity identified by id
+	Read(ctx context.Context, id string) (interface{}, error)
+
+	// Returns a slice of entities that matches the criteria specified by the options
+	ReadAll(ctx context.Context, options ...QueryOptions) (interface{}, error)
+
+	// Return the entity name (used for logs and messages)
+	EntityName() string
+
+	// Returns a newly created instance. Should be as simple as return &Thing{}
+	NewInstance() interface{}
+}
+
+/*
+PersistableV2 is the context-aware version of the Persistable interface. It must be implemented by RepositoryV2
+implementations to allow the POST, PUT, PATCH and DELETE methods
+*/
+type PersistableV2 interface {
+	// Adds the entity to the repository and returns the newly created id
+	Save(ctx context.Context, entity interface{}) (string, error)
+
+	// Updates the entity identified by id. Optionally select the fields to be updated
+	Update(ctx context.Context, id string, entity interface{}, cols ...string) error
+
+	// Delete the entity identified by id
+	Delete(ctx context.Context, id string) error
+}
+
+// CursorPaginatedV2 is the context-aware version of the CursorPaginated interface
+type CursorPaginatedV2 interface {
+	// Returns up to options.Max entities (all if Max is 0) that come after the cursor. See CursorPaginated
+	ReadAllAfter(ctx context.Context, cursor Cursor, options QueryOptions) (interface{}, Cursor, error)
+}
+
+// StreamableV2 is the context-aware version of the Streamable interface
+type StreamableV2 interface {
+	// Calls yield for each entity that matches the criteria specified by the options, in order. See Streamable
+	ReadAllIter(ctx context.Context, yield func(entity interface{}) error, options ...QueryOptions) error
+}
+
+// BulkPersistableV2 is the context-aware version of the BulkPersistable interface
+type BulkPersistableV2 interface {
+	// Adds the entities to the repository. See BulkPersistable
+	SaveAll(ctx context.Context, entities []interface{}) ([]string, []error)
+
+	// Deletes the entities identified by ids. See BulkPersistable
+	DeleteAll(ctx context.Context, ids []string) []error
+}
+
+/*
+FromV2 returns a RepositoryConstructor for a RepositoryV2, so it can be used with the handler functions. Ex:
+rest.Get(rest.FromV2(thingsRepository))
+*/
+func FromV2(repo RepositoryV2) RepositoryConstructor {
+	return func(ctx context.Context) Repository {
+		bound := boundRepository{ctx: ctx, repo: repo}
+		if rp, ok := repo.(PersistableV2); ok {
+			return &boundPersistable{boundRepository: bound, rp: rp}
+		}
+		return &bound
+	}
+}
+
+/*
+AdaptRepository wraps a Repository as a RepositoryV2. If it also implements Persistable, the result implements
+PersistableV2. The contexts passed to the methods are ignored, as Repository implementations receive it from their
+RepositoryConstructor
+*/
+func AdaptRepository(repo Repository) RepositoryV2 {
+	if rp, ok := repo.(Persistable); ok {
+		return persistableAdapter{repositoryAdapter: repositoryAdapter{repo}, rp: rp}
+	}
+	return repositoryAdapter{repo}
+}
+
+// repository returns the repository used by the controller: RepositoryV2 if set, or the Repository adapted to it
+func (c *Controller) repository() RepositoryV2 {
+	if c.RepositoryV2 != nil {
+		return c.RepositoryV2
+	}
+	switch repo := c.Repository.(type) {
+	case nil:
+		return nil
+	case v2Binding:
+		return repo.v2()
+	}
+	return AdaptRepository(c.Repository)
+}
+
+// implementation returns the repository implementation, to check the optional interfaces it implements
+func (c *Controller) implementation() interface{} {
+	repo := c.repository()
+	if a, ok := repo.(adapter); ok {
+		return a.underlying()
+	}
+	return repo
+}
+
+// cursorPaginated returns the repository as a CursorPaginatedV2, adapting it if it implements CursorPaginated
+func (c *Controller) cursorPaginated() (CursorPaginatedV2, bool) {
+	switch impl := c.implementation().(type) {
+	case CursorPaginatedV2:
+		return impl, true
+	case CursorPaginated:
+		return cursorPaginatedAdapter{impl}, true
+	}
+	return nil, false
+}
+
+// streamable returns the repository as a StreamableV2, adapting it if it implements Streamable
+func (c *Controller) streamable() (StreamableV2, bool) {
+	switch impl := c.implementation().(type) {
+	case StreamableV2:
+		return impl, true
+	case Streamable:
+		return streamableAdapter{impl}, true
+	}
+	return nil, false
+}
+
+// bulkPersistable returns the repository as a BulkPersistableV2, adapting it if it implements BulkPersistable
+func (c *Controller) bulkPersistable() (BulkPersistableV2, bool) {
+	switch impl := c.implementation().(type) {
+	case BulkPersistableV2:
+		return impl, true
+	case BulkPersistable:
+		return bulkPersistableAdapter{impl}, true
+	}
+	return nil, false
+}
+
+// adapter is implemented by the RepositoryV2 adapters, to expose the repositories they wrap
+type adapter interface {
+	underlying() interface{}
+}
+
+// v2Binding is implemented by the Repositories returned by FromV2
+type v2Binding interface {
+	v2() RepositoryV2
+}
+
+// boundRepository implements Repository for a RepositoryV2, with the context of the request
+type boundRepository struct {
+	ctx  context.Context
+	repo RepositoryV2
+}
+
+func (b *boundRepository) v2() RepositoryV2 { return b.repo }
+
+func (b *boundRepository) Count(options ...QueryOptions) (int64, error) {
+	return b.repo.Count(b.ctx, options...)
+}
+
+func (b *boundRepository) Read(id string) (interface{}, error) {
+	return b.repo.Read(b.ctx, id)
+}
+
+func (b *boundRepository) ReadAll(options ...QueryOptions) (interface{}, error) {
+	return b.repo.ReadAll(b.ctx, options...)
+}
+
+func (b *boundRepository) EntityName() string { return b.repo.EntityName() }
+
+func (b *boundRepository) NewInstance() interface{} { return b.repo.NewInstance() }
+
+type boundPersistable struct {
+	boundRepository
+	rp PersistableV2
+}
+
+func (b *boundPersistable) Save(entity interface{}) (string, error) {
+	return b.rp.Save(b.ctx, entity)
+}
+
+func (b *boundPersistable) Update(id string, entity interface{}, cols ...string) error {
+	return b.rp.Update(b.ctx, id, entity, cols...)
+}
+
+func (b *boundPersistable) Delete(id string) error {
+	return b.rp.Delete(b.ctx, id)
+}
+
+// repositoryAdapter implements RepositoryV2 for a Repository
+type repositoryAdapter struct {
+	Repository
+}
+
+func (a repositoryAdapter) underlying() interface{} { return a.Repository }
+
+func (a repositoryAdapter) Count(_ context.Context, options ...QueryOptions) (int64, error) {
+	return a.Repository.Count(options...)
+}
+
+func (a repositoryAdapter) Read(_ context.Context, id string) (interface{}, error) {
+	return a.Repository.Read(id)
+}
+
+func (a repositoryAdapter) ReadAll(_ context.Context, options ...QueryOptions) (interface{}, error) {
+	return a.Repository.ReadAll(options...)
+}
+
+type persistableAdapter struct {
+	repositoryAdapter
+	rp Persistable
+}
+
+func (a persistableAdapter) Save(_ context.Context, entity interface{}) (string, error) {
+	return a.rp.Save(entity)
+}
+
+func (a persistableAdapter) Update(_ context.Context, id string, entity interface{}, cols ...string) error {
+	return a.rp.Update(id, entity, cols...)
+}
+
+func (a persistableAdapter) Delete(_ context.Context, id string) error {
+	return a.rp.Delete(id)
+}
+
+type cursorPaginatedAdapter struct {
+	cp CursorPaginated
+}
+
+func (a cursorPaginatedAdapter) ReadAllAfter(_ context.Context, cursor Cursor, options QueryOptions) (interface{}, Cursor, error) {
+	return a.cp.ReadAllAfter(cursor, options)
+}
+
+type streamableAdapter struct {
+	s Streamable
+}
+
+func (a streamableAdapter) ReadAllIter(_ context.Context, yield func(entity interface{}) error, options ...QueryOptions) error {
+	return a.s.ReadAllIter(yield, options...)
+}
+
+type bulkPersistableAdapter struct {
+	bp BulkPersistable
+}
+
+func (a bulkPersistableAdapter) SaveAll(_ context.Context, entities []interface{}) ([]string, []error) {
+	return a.bp.SaveAll(entities)
+}
+
+func (a bulkPersistableAdapter) DeleteAll(_ context.Context, ids []string) []error {
+	return a.bp.DeleteAll(ids)
+}
@@ -0,0 +1,247 @@
+package rest_test
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/deluan/rest"
+	"github.com/deluan/rest/examples"
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+// contextRepository is a RepositoryV2 that stores the SampleModels in memory, and keeps the last context received
+type contextRepository struct {
+	data map[string]examples.SampleModel
+	ctx  context.Context
+}
+
+func (r *contextRepository) Count(ctx context.Context, options ...rest.QueryOptions) (int64, error) {
+	r.ctx = ctx
+	return int64(len(r.data)), nil
+}
+
+func (r *contextRepository) Read(ctx context.Context, id string) (interface{}, error) {
+	r.ctx = ctx
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	if m, ok := r.data[id]; ok {
+		return m, nil
+	}
+	return nil, rest.ErrNotFound
+}
+
+func (r *contextRepository) ReadAll(ctx context.Context, options ...rest.QueryOptions) (interface{}, error) {
+	r.ctx = ctx
+	all := make([]examples.SampleModel, 0)
+	for _, m := range r.data {
+		all = append(all, m)
+	}
+	return all, nil
+}
+
+func (r *contextRepository) EntityName() string { return "sample" }
+
+func (r *contextRepository) NewInstance() interface{} { return &examples.SampleModel{} }
+
+func (r *contextRepository) Save(ctx context.Context, entity interface{}) (string, error) {
+	r.ctx = ctx
+	m := entity.(*examples.SampleModel)
+	r.data[m.ID] = *m
+	return m.ID, nil
+}
+
+func (r *contextRepository) Update(ctx context.Context, id string, entity interface{}, cols ...string) error {
+	r.ctx = ctx
+	m := entity.(*examples.SampleModel)
+	m.ID = id
+	r.data[id] = *m
+	return nil
+}
+
+func (r *contextRepository) Delete(ctx context.Context, id string) error {
+	r.ctx = ctx
+	delete(r.data, id)
+	return nil
+}
+
+// optionalContextRepository adds the context-aware optional interfaces to contextRepository, recording the calls
+type optionalContextRepository struct {
+	contextRepository
+	calls []string
+}
+
+func (r *optionalContextRepository) ReadAllAfter(ctx context.Context, cursor rest.Cursor, options rest.QueryOptions) (interface{}, rest.Cursor, error) {
+	r.ctx = ctx
+	r.calls = append(r.calls, "ReadAllAfter")
+	return []examples.SampleModel{r.data["1"]}, nil, nil
+}
+
+func (r *optionalContextRepository) ReadAllIter(ctx context.Context, yield func(entity interface{}) error, options ...rest.QueryOptions) error {
+	r.ctx = ctx
+	r.calls = append(r.calls, "ReadAllIter")
+	return yield(r.data["1"])
+}
+
+func (r *optionalContextRepository) SaveAll(ctx context.Context, entities []interface{}) ([]string, []error) {
+	r.ctx = ctx
+	r.calls = append(r.calls, "SaveAll")
+	ids := make([]string, len(entities))
+	for i, e := range entities {
+		ids[i], _ = r.Save(ctx, e)
+	}
+	return ids, make([]error, len(entities))
+}
+
+func (r *optionalContextRepository) DeleteAll(ctx context.Context, ids []string) []error {
+	r.ctx = ctx
+	r.calls = append(r.calls, "DeleteAll")
+	for _, id := range ids {
+		_ = r.Delete(ctx, id)
+	}
+	return make([]error, len(ids))
+}
+
+func TestController_RepositoryV2(t *testing.T) {
+	Convey("Given a RepositoryV2 used with the handler functions", t, func() {
+		repo := &contextRepository{data: map[string]examples.SampleModel{"1": {ID: "1", Name: "Joe", Age: 30}}}
+		newRepository := rest.FromV2(repo)
+
+		Convey("When I call Get", func() {
+			req, res := createRequestResponse("GET", "/sample?:id=1", nil)
+			rest.Get(newRepository, logger)(res, req)
+
+			Convey("It returns the record", func() {
+				So(res.Code, ShouldEqual, 200)
+				So(res.Body.String(), ShouldEqual, `{"ID":"1","Name":"Joe","Age":30}`)
+			})
+
+			Convey("It passes down the context", func() {
+				So(repo.ctx.Value("test_key"), ShouldEqual, "test_value")
+			})
+		})
+
+		Convey("When I call Put", func() {
+			req, res := createRequestResponse("PUT", "/sample?:id=1", aRecordReader("1", "John", 31))
+			rest.Put(newRepository, logger)(res, req)
+
+			Convey("It updates the record", func() {
+				So(res.Code, ShouldEqual, 200)
+				So(repo.data["1"].Name, ShouldEqual, "John")
+			})
+		})
+
+		Convey("When I call Delete", func() {
+			req, res := createRequestResponse("DELETE", "/sample?:id=1", nil)
+			rest.Delete(newRepository, logger)(res, req)
+
+			Convey("It deletes the record", func() {
+				So(res.Code, ShouldEqual, 200)
+				So(repo.data, ShouldBeEmpty)
+			})
+		})
+
+		Convey("When the request is cancelled", func() {
+			ctx, cancel := context.WithCancel(context.Background())
+			cancel()
+			req, res := createRequestResponse("GET", "/sample?:id=1", nil)
+			rest.Get(newRepository, logger)(res, req.WithContext(ctx))
+
+			Convey("The repository receives the cancelled context", func() {
+				So(repo.ctx.Err(), ShouldEqual, context.Canceled)
+				So(res.Code, ShouldEqual, 500)
+			})
+		})
+	})
+
+	Convey("Given a Controller with a RepositoryV2", t, func() {
+		repo := &contextRepository{data: map[string]examples.SampleModel{}}
+		c := rest.Controller{RepositoryV2: repo, Logger: logger}
+
+		Convey("When I call Post", func() {
+			req, res := createRequestResponse("POST", "/sample", aRecordReader("2", "Cecilia", 22))
+			c.Post(res, req)
+
+			Convey("It saves the record, passing down the context", func() {
+				So(res.Code, ShouldEqual, 200)
+				So(repo.data["2"].Name, ShouldEqual, "Cecilia")
+				So(repo.ctx.Value("test_key"), ShouldEqual, "test_value")
+			})
+		})
+
+		Convey("When I call GetAll", func() {
+			req, res := createRequestResponse("GET", "/sample", nil)
+			c.GetAll(res, req)
+
+			Convey("It returns the records", func() {
+				So(res.Code, ShouldEqual, 200)
+				So(res.Body.String(), ShouldEqual, "[]")
+				So(res.Header().Get("X-Total-Count"), ShouldEqual, "0")
+			})
+		})
+	})
+
+	Convey("Given a RepositoryV2 that implements the context-aware optional interfaces", t, func() {
+		repo := &optionalContextRepository{contextRepository: contextRepository{
+			data: map[string]examples.SampleModel{"1": {ID: "1", Name: "Joe", Age: 30}},
+		}}
+		newRepository := rest.FromV2(repo)
+
+		Convey("When I call GetAll in cursor mode", func() {
+			req, res := createRequestResponse("GET", "/sample?_cursor=", nil)
+			rest.GetAll(newRepository, logger)(res, req)
+
+			Convey("It calls ReadAllAfter, passing down the context", func() {
+				So(res.Code, ShouldEqual, 200)
+				So(repo.calls, ShouldResemble, []string{"ReadAllAfter"})
+				So(repo.ctx.Value("test_key"), ShouldEqual, "test_value")
+			})
+		})
+
+		Convey("When I call GetAll", func() {
+			req, res := createRequestResponse("GET", "/sample", nil)
+			rest.GetAll(newRepository, logger)(res, req)
+
+			Convey("It streams the entities with ReadAllIter, passing down the context", func() {
+				So(res.Code, ShouldEqual, 200)
+				So(res.Body.String(), ShouldEqual, `[{"ID":"1","Name":"Joe","Age":30}]`)
+				So(repo.calls, ShouldResemble, []string{"ReadAllIter"})
+				So(repo.ctx.Value("test_key"), ShouldEqual, "test_value")
+			})
+		})
+
+		Convey("When I call BulkPost", func() {
+			body := strings.NewReader(`[{"ID":"2","Name":"Cecilia","Age":22}]`)
+			req, res := createRequestResponse("POST", "/sample/bulk", body)
+			rest.BulkPost(newRepository, logger)(res, req)
+
+			Convey("It calls SaveAll, passing down the context", func() {
+				So(res.Code, ShouldEqual, 200)
+				So(repo.calls, ShouldResemble, []string{"SaveAll"})
+				So(repo.ctx.Value("test_key"), ShouldEqual, "test_value")
+			})
+		})
+
+		Convey("When I call BulkDelete", func() {
+			req, res := createRequestResponse("DELETE", "/sample?id=1", nil)
+			rest.BulkDelete(newRepository, logger)(res, req)
+
+			Convey("It calls DeleteAll, passing down the context", func() {
+				So(res.Code, ShouldEqual, 200)
+				So(repo.calls, ShouldResemble, []string{"DeleteAll"})
+				So(repo.ctx.Value("test_key"), ShouldEqual, "test_value")
+				So(repo.data, ShouldBeEmpty)
+			})
+		})
+	})
+
+	Convey("Given a Repository adapted to RepositoryV2", t, func() {
+		Convey("It implements PersistableV2 only if the Repository is Persistable", func() {
+			_, ok := rest.AdaptRepository(examples.NewPersistableSampleRepository(nil)).(rest.PersistableV2)
+			So(ok, ShouldBeTrue)
+			_, ok = rest.AdaptRepository(examples.NewSampleRepository(nil)).(rest.PersistableV2)
+			So(ok, ShouldBeFalse)
+		})
+	})
+}
@@ -0,0 +1,104 @@
+package rest
+
+import (
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+/*
+Resource returns a http.Handler that serves all verbs for an entity, so the routes don't need to be mapped one by
+one. It dispatches the requests by path and method:
+
+	GET    /        -> GetAll
+	POST   /        -> Post
+	GET    /{id}    -> Get
+	PUT    /{id}    -> Put
+	PATCH  /{id}    -> Patch
+	DELETE /{id}    -> Delete
+
+HEAD is served as GET. Paths with more than one segment get a 404 http status, and methods not listed above (or the
+ones that change the entity, if the repository does not implement Persistable) get a 405 http status, with the
+allowed methods in the Allow header. The id is taken from the path, so the IDExtractor is not used. To mount it under
+a prefix, use http.StripPrefix. Ex:
+
+	http.Handle("/thing/", http.StripPrefix("/thing", rest.Resource(NewThingsRepository, logger)))
+*/
+func Resource(newRepository RepositoryConstructor, logger ...Logger) http.Handler {
+	return Configure(loggerOptions(logger)...).Resource(newRepository)
+}
+
+// Resource returns a http.Handler that serves all verbs for an entity. See the Resource function
+func (h Handlers) Resource(newRepository RepositoryConstructor) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		c := createController(newRepository, r.Context(), h.options)
+		id, isItem, ok := resourceID(r.URL.EscapedPath())
+		if !ok {
+			c.respondWithError(w, r, http.StatusNotFound, "404 Not Found")
+			return
+		}
+		if isItem {
+			c.IDExtractor = func(*http.Request) string { return id }
+		}
+
+		handler, allowed := c.resourceHandler(r.Method, isItem)
+		if handler == nil {
+			w.Header().Set("Allow", strings.Join(allowed, ", "))
+			c.respondWithError(w, r, http.StatusMethodNotAllowed, "405 Method Not Allowed")
+			return
+		}
+		handler(w, r)
+	})
+}
+
+// resourceID parses the escaped path of a Resource request, returning the id, if it is an item path, and false if
+// the path is neither the collection nor an item. Escaped slashes (%2F) are allowed in ids
+func resourceID(path string) (id string, isItem bool, ok bool) {
+	path = strings.Trim(path, "/")
+	if path == "" {
+		return "", false, true
+	}
+	if strings.Contains(path, "/") {
+		return "", false, false
+	}
+	id, err := url.PathUnescape(path)
+	if err != nil {
+		return "", false, false
+	}
+	return id, true, true
+}
+
+// resourceHandler returns the Controller method for the request method, or nil and the allowed methods
+func (c *Controller) resourceHandler(method string, isItem bool) (http.HandlerFunc, []string) {
+	_, persistable := c.repository().(PersistableV2)
+	handlers := map[string]http.HandlerFunc{}
+	if isItem {
+		handlers[http.MethodGet] = c.Get
+		if persistable {
+			handlers[http.MethodPut] = c.Put
+			handlers[http.MethodPatch] = c.Patch
+			handlers[http.MethodDelete] = c.Delete
+		}
+	} else {
+		handlers[http.MethodGet] = c.GetAll
+		if persistable {
+			handlers[http.MethodPost] = c.Post
+		}
+	}
+	handlers[http.MethodHead] = handlers[http.MethodGet]
+
+	if handler, ok := handlers[method]; ok {
+		return handler, nil
+	}
+	var allowed []string
+	for _, m := range resourceMethods {
+		if _, ok := handlers[m]; ok {
+			allowed = append(allowed, m)
+		}
+	}
+	return nil, allowed
+}
+
+var resourceMethods = []string{
+	http.MethodGet, http.MethodHead, http.MethodPost, http.MethodPut, http.MethodPatch, http.MethodDelete,
+}
@@ -0,0 +1,148 @@
+package rest_test
+
+import (
+	"context"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/deluan/rest"
+	"github.com/deluan/rest/examples"
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestResource(t *testing.T) {
+	Convey("Given a Resource for a persistable repository, mounted under a prefix", t, func() {
+		repo := examples.NewPersistableSampleRepository(nil)
+		resource := rest.Resource(func(ctx context.Context) rest.Repository {
+			repo.Context = ctx
+			return repo
+		}, logger)
+		mux := http.NewServeMux()
+		mux.Handle("/sample/", http.StripPrefix("/sample", resource))
+		joe := aRecord("Joe", 30)
+		id, _ := repo.Save(&joe)
+
+		Convey("When I GET the collection", func() {
+			req, res := createRequestResponse("GET", "/sample/", nil)
+			mux.ServeHTTP(res, req)
+
+			Convey("It returns all items", func() {
+				So(res.Code, ShouldEqual, 200)
+				So(res.Header().Get("X-Total-Count"), ShouldEqual, "1")
+			})
+
+			Convey("It passes down the context", func() {
+				So(repo.Context.Value("test_key"), ShouldEqual, "test_value")
+			})
+		})
+
+		Convey("When I GET an item", func() {
+			req, res := createRequestResponse("GET", "/sample/"+id, nil)
+			mux.ServeHTTP(res, req)
+
+			Convey("It returns the item identified by the path", func() {
+				So(res.Code, ShouldEqual, 200)
+				So(res.Body.String(), ShouldContainSubstring, `"Name":"Joe"`)
+			})
+		})
+
+		Convey("When I POST to the collection", func() {
+			req, res := createRequestResponse("POST", "/sample/", aRecordReader("", "Cecilia", 22))
+			mux.ServeHTTP(res, req)
+
+			Convey("It creates a new item", func() {
+				So(res.Code, ShouldEqual, 200)
+				count, _ := repo.Count()
+				So(count, ShouldEqual, 2)
+			})
+		})
+
+		Convey("When I PUT an item", func() {
+			req, res := createRequestResponse("PUT", "/sample/"+id, aRecordReader(id, "John", 31))
+			mux.ServeHTTP(res, req)
+
+			Convey("It updates the item identified by the path", func() {
+				So(res.Code, ShouldEqual, 200)
+				updated, _ := repo.Read(id)
+				So(updated.(examples.SampleModel).Name, ShouldEqual, "John")
+			})
+		})
+
+		Convey("When I PATCH an item", func() {
+			req, res := createRequestResponse("PATCH", "/sample/"+id, strings.NewReader(`{"Age":31}`))
+			mux.ServeHTTP(res, req)
+
+			Convey("It updates the item identified by the path", func() {
+				So(res.Code, ShouldEqual, 200)
+				updated, _ := repo.Read(id)
+				So(updated.(examples.SampleModel).Age, ShouldEqual, 31)
+			})
+		})
+
+		Convey("When I DELETE an item", func() {
+			req, res := createRequestResponse("DELETE", "/sample/"+id, nil)
+			mux.ServeHTTP(res, req)
+
+			Convey("It deletes the item identified by the path", func() {
+				So(res.Code, ShouldEqual, 200)
+				count, _ := repo.Count()
+				So(count, ShouldEqual, 0)
+			})
+		})
+
+		Convey("When I DELETE the collection", func() {
+			req, res := createRequestResponse("DELETE", "/sample/", nil)
+			mux.ServeHTTP(res, req)
+
+			Convey("It returns 405 http status, with the allowed methods", func() {
+				So(res.Code, ShouldEqual, 405)
+				So(res.Header().Get("Allow"), ShouldEqual, "GET, HEAD, POST")
+			})
+		})
+
+		Convey("When I POST to an item", func() {
+			req, res := createRequestResponse("POST", "/sample/"+id, aRecordReader(id, "John", 31))
+			mux.ServeHTTP(res, req)
+
+			Convey("It returns 405 http status, with the allowed methods", func() {
+				So(res.Code, ShouldEqual, 405)
+				So(res.Header().Get("Allow"), ShouldEqual, "GET, HEAD, PUT, PATCH, DELETE")
+			})
+		})
+
+		Convey("When I GET a nested path", func() {
+			req, res := createRequestResponse("GET", "/sample/"+id+"/other", nil)
+			mux.ServeHTTP(res, req)
+
+			Convey("It returns 404 http status", func() {
+				So(res.Code, ShouldEqual, 404)
+			})
+		})
+	})
+
+	Convey("Given a Resource for a read-only repository", t, func() {
+		repo := examples.NewSampleRepository(nil)
+		resource := rest.Resource(func(ctx context.Context) rest.Repository { return repo }, logger)
+
+		Convey("When I POST to the collection", func() {
+			req, res := createRequestResponse("POST", "/", aRecordReader("", "Cecilia", 22))
+			resource.ServeHTTP(res, req)
+
+			Convey("It returns 405 http status, with the allowed methods", func() {
+				So(res.Code, ShouldEqual, 405)
+				So(res.Header().Get("Allow"), ShouldEqual, "GET, HEAD")
+			})
+		})
+
+		Convey("When I DELETE an item", func() {
+			req, res := createRequestResponse("DELETE", "/1", nil)
+			resource.ServeHTTP(res, req)
+
+			Convey("It returns 405 http status, with the allowed methods", func() {
+				So(res.Code, ShouldEqual, 405)
+				So(res.Header().Get("Allow"), ShouldEqual, "GET, HEAD")
+			})
+		})
+	})
+}
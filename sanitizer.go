@@ -0,0 +1,92 @@
+package rest
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"net/http"
+	"sync"
+)
+
+/*
+ErrorSanitizer returns the message sent to the client for an internal error (any error that results in a 5xx http
+status without a custom message), and an optional correlation ID. When the ID is not empty, it is logged with the
+full error, and sent to the client in the response body and in the X-Correlation-ID header, so the logs can be
+matched with the responses. See VerboseErrors and ProductionErrors
+*/
+type ErrorSanitizer func(err error) (message string, correlationID string)
+
+// VerboseErrors sends the error message to the client. This is the default ErrorSanitizer, useful for development
+func VerboseErrors(err error) (string, string) {
+	return err.Error(), ""
+}
+
+// ProductionErrors hides the error from the client, sending a generic message and a random correlation ID instead
+func ProductionErrors(err error) (string, string) {
+	return "Internal server error", newCorrelationID()
+}
+
+var (
+	errorSanitizerMu sync.RWMutex
+	errorSanitizer   ErrorSanitizer = VerboseErrors
+)
+
+/*
+SetErrorSanitizer sets the default ErrorSanitizer for the controllers created by the handler functions. Ex:
+rest.SetErrorSanitizer(rest.ProductionErrors)
+*/
+func SetErrorSanitizer(sanitizer ErrorSanitizer) {
+	errorSanitizerMu.Lock()
+	defer errorSanitizerMu.Unlock()
+	errorSanitizer = sanitizer
+}
+
+func defaultErrorSanitizer() ErrorSanitizer {
+	errorSanitizerMu.RLock()
+	defer errorSanitizerMu.RUnlock()
+	return errorSanitizer
+}
+
+func newCorrelationID() string {
+	buf := make([]byte, 8)
+	if _, err := rand.Read(buf); err != nil {
+		return "unknown"
+	}
+	return hex.EncodeToString(buf)
+}
+
+/*
+respondWithInternalError logs the error and sends it to the client, sanitized by the controller's ErrorSanitizer.
+action is used in the log message. Ex: "Reading thing(id:1)"
+*/
+func (c *Controller) respondWithInternalError(w http.ResponseWriter, r *http.Request, code int, action string, err error) {
+	msg, id := c.sanitize(r, action, err)
+	if id == "" {
+		c.respondWithError(w, r, code, msg)
+		return
+	}
+	w.Header().Set("X-Correlation-ID", id)
+	if c.ProblemDetails {
+		c.respondWithProblem(w, r, Problem{Status: code, Detail: msg, CorrelationID: id})
+		return
+	}
+	c.respond(w, r, code, map[string]string{"error": msg, "correlation_id": id})
+}
+
+// sanitize logs the internal error, and returns the message and the correlation ID to be sent to the client
+func (c *Controller) sanitize(r *http.Request, action string, err error) (string, string) {
+	sanitizer := c.ErrorSanitizer
+	if sanitizer == nil {
+		sanitizer = defaultErrorSanitizer()
+	}
+	if sanitizer == nil {
+		sanitizer = VerboseErrors
+	}
+	c.recordAccessError(r, err)
+	msg, id := sanitizer(err)
+	if id == "" {
+		c.errorf(r, "%s: %v", action, err)
+	} else {
+		c.errorf(r, "%s: %v (correlation_id: %s)", action, err, id)
+	}
+	return msg, id
+}
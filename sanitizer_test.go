@@ -0,0 +1,123 @@
+package rest_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/deluan/rest"
+	"github.com/deluan/rest/examples"
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+// recordingLogger keeps the logged messages
+type recordingLogger struct {
+	warnings []string
+	errors   []string
+}
+
+func (l *recordingLogger) Warnf(format string, args ...interface{}) {
+	l.warnings = append(l.warnings, fmt.Sprintf(format, args...))
+}
+
+func (l *recordingLogger) Errorf(format string, args ...interface{}) {
+	l.errors = append(l.errors, fmt.Sprintf(format, args...))
+}
+
+func TestController_ErrorSanitizer(t *testing.T) {
+	Convey("Given the ProductionErrors sanitizer", t, func() {
+		rest.SetErrorSanitizer(rest.ProductionErrors)
+		defer rest.SetErrorSanitizer(rest.VerboseErrors)
+		repo := examples.NewPersistableSampleRepository(nil)
+		repo.Error = errors.New("pq: syntax error at or near \"FROM\" (host db-1.internal)")
+		log := &recordingLogger{}
+		newRepository := func(ctx context.Context) rest.Repository { return repo }
+
+		Convey("When I call Get and the repository fails", func() {
+			req, res := createRequestResponse("GET", "/sample?:id=1", nil)
+			rest.Get(newRepository, log)(res, req)
+
+			var body map[string]string
+			_ = json.Unmarshal(res.Body.Bytes(), &body)
+			id := res.Header().Get("X-Correlation-ID")
+
+			Convey("It returns a generic message and the correlation ID", func() {
+				So(res.Code, ShouldEqual, 500)
+				So(id, ShouldNotBeEmpty)
+				So(body, ShouldResemble, map[string]string{"error": "Internal server error", "correlation_id": id})
+			})
+
+			Convey("It logs the full error with the correlation ID", func() {
+				So(log.errors, ShouldHaveLength, 1)
+				So(log.errors[0], ShouldContainSubstring, "db-1.internal")
+				So(log.errors[0], ShouldContainSubstring, id)
+			})
+		})
+
+		Convey("When I call it with the ProblemDetails mode enabled", func() {
+			rest.EnableProblemDetails(true)
+			defer rest.EnableProblemDetails(false)
+			req, res := createRequestResponse("GET", "/sample?:id=1", nil)
+			rest.Get(newRepository, log)(res, req)
+
+			Convey("It returns the correlation ID in the problem", func() {
+				var problem rest.Problem
+				_ = json.Unmarshal(res.Body.Bytes(), &problem)
+				So(problem.Detail, ShouldEqual, "Internal server error")
+				So(problem.CorrelationID, ShouldEqual, res.Header().Get("X-Correlation-ID"))
+			})
+		})
+
+		Convey("When I call BulkDelete and the repository fails", func() {
+			req, res := createRequestResponse("DELETE", "/sample?id=1", nil)
+			rest.BulkDelete(newRepository, log)(res, req)
+
+			Convey("It returns a generic message and the correlation ID in the result", func() {
+				var results []rest.BulkResult
+				_ = json.Unmarshal(res.Body.Bytes(), &results)
+				So(results, ShouldHaveLength, 1)
+				So(results[0].Error, ShouldEqual, "Internal server error")
+				So(results[0].CorrelationID, ShouldNotBeEmpty)
+				So(log.errors[0], ShouldContainSubstring, results[0].CorrelationID)
+			})
+		})
+
+		Convey("When the repository returns a mapped error", func() {
+			repo.Error = rest.ErrNotFound
+			req, res := createRequestResponse("GET", "/sample?:id=1", nil)
+			rest.Get(newRepository, log)(res, req)
+
+			Convey("It is not sanitized", func() {
+				So(res.Code, ShouldEqual, 404)
+				So(res.Body.String(), ShouldEqual, `{"error":"sample(id:1) not found"}`)
+			})
+		})
+
+		Convey("When I call a Controller created without an ErrorSanitizer", func() {
+			c := &rest.Controller{Repository: repo, Logger: log}
+			req, res := createRequestResponse("GET", "/sample?:id=1", nil)
+			c.Get(res, req)
+
+			Convey("It uses the package default sanitizer", func() {
+				So(res.Code, ShouldEqual, 500)
+				So(res.Body.String(), ShouldNotContainSubstring, "db-1.internal")
+				So(res.Header().Get("X-Correlation-ID"), ShouldNotBeEmpty)
+			})
+		})
+	})
+
+	Convey("Given the default sanitizer", t, func() {
+		handler, repo := createPersistableHandler(rest.Get)
+		repo.Error = errors.New("unknown error")
+		req, res := createRequestResponse("GET", "/sample?:id=1", nil)
+		handler(res, req)
+
+		Convey("It returns the error message", func() {
+			So(res.Code, ShouldEqual, 500)
+			So(res.Body.String(), ShouldEqual, `{"error":"unknown error"}`)
+			So(res.Header().Get("X-Correlation-ID"), ShouldBeEmpty)
+		})
+	})
+}
@@ -0,0 +1,45 @@
+//go:build go1.21
+// +build go1.21
+
+package rest_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"log/slog"
+	"testing"
+
+	"github.com/deluan/rest"
+	"github.com/deluan/rest/examples"
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestController_SlogLogger(t *testing.T) {
+	Convey("Given a handler with a slog StructuredLogger", t, func() {
+		var buf bytes.Buffer
+		l := slog.New(slog.NewJSONHandler(&buf, &slog.HandlerOptions{Level: slog.LevelWarn}))
+		repo := examples.NewPersistableSampleRepository(nil)
+		handler := rest.Delete(func(ctx context.Context) rest.Repository { return repo }, rest.NewSlogLogger(l))
+
+		Convey("When I call Delete for a missing record", func() {
+			req, res := createRequestResponse("DELETE", "/sample?:id=1", nil)
+			handler(res, req)
+
+			Convey("It logs a warning with the request-scoped fields", func() {
+				So(res.Code, ShouldEqual, 404)
+				var entry map[string]interface{}
+				So(json.Unmarshal(buf.Bytes(), &entry), ShouldBeNil)
+				delete(entry, "time")
+				So(entry, ShouldResemble, map[string]interface{}{
+					"level":  "WARN",
+					"msg":    "Deleting sample(id:1): data not found",
+					"method": "DELETE",
+					"path":   "/sample",
+					"entity": "sample",
+					"id":     "1",
+				})
+			})
+		})
+	})
+}
@@ -0,0 +1,196 @@
+package rest
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+)
+
+/*
+Streamable can be implemented by repositories in addition to the Repository interface, to send large collections in
+GetAll without loading them all in memory. The entities are written to the response as they are yielded, as a JSON
+array or as NDJSON (when the client accepts application/x-ndjson or sends _format=ndjson). Other formats, and
+requests in cursor mode, keep using ReadAll. As the response is sent before all entities are read, streamed
+responses do not have ETag and Last-Modified headers, and errors after the first entity can only be logged. See also
+StreamableV2.
+*/
+type Streamable interface {
+	// Calls yield for each entity that matches the criteria specified by the options, in order. If yield returns
+	// an error (ex: the client disconnected), the iteration must stop and return this error
+	ReadAllIter(yield func(entity interface{}) error, options ...QueryOptions) error
+}
+
+/*
+StreamRenderer can be implemented by renderers that are able to write collections incrementally, enabling streaming
+for repositories that implement the Streamable interface
+*/
+type StreamRenderer interface {
+	Renderer
+
+	// Returns a RenderStream that writes the entities of a collection to w
+	NewStream(w io.Writer) RenderStream
+}
+
+// RenderStream writes the entities of a collection, one at a time
+type RenderStream interface {
+	// Writes the next entity of the collection
+	Write(entity interface{}) error
+
+	// Finishes the collection. It is not called if the stream is interrupted
+	Close() error
+}
+
+// streamFlushInterval is the number of entities written to the response between flushes
+var streamFlushInterval = 100
+
+// streamRenderer returns the renderer negotiated with the client, if the response can be streamed
+func (c *Controller) streamRenderer(r *http.Request) (StreamRenderer, bool) {
+	if _, ok := c.streamable(); !ok {
+		return nil, false
+	}
+	if _, cursorMode := r.URL.Query()["_cursor"]; cursorMode {
+		return nil, false
+	}
+	renderer, ok := c.negotiate(r)
+	if !ok {
+		return nil, false
+	}
+	sr, ok := renderer.(StreamRenderer)
+	return sr, ok
+}
+
+// stream writes the entities yielded by the repository to the response, flushing it every streamFlushInterval
+// entities. If the repository fails before yielding the first entity, it responds with an error as usual
+func (c *Controller) stream(w http.ResponseWriter, r *http.Request, renderer StreamRenderer, options QueryOptions) {
+	ctx := r.Context()
+	flusher, _ := w.(http.Flusher)
+	var stream RenderStream
+	start := func() {
+		w.Header().Set("Content-Type", renderer.ContentType())
+		w.WriteHeader(http.StatusOK)
+		stream = renderer.NewStream(w)
+	}
+	count := 0
+	streamable, _ := c.streamable()
+	err := streamable.ReadAllIter(ctx, func(entity interface{}) error {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		if stream == nil {
+			start()
+		}
+		if err := stream.Write(entity); err != nil {
+			return err
+		}
+		count++
+		if flusher != nil && count%streamFlushInterval == 0 {
+			flusher.Flush()
+		}
+		return nil
+	}, options)
+	switch {
+	case errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded):
+		c.warnf(r, "Streaming %s: interrupted after %d entities: %v", c.repository().EntityName(), count, err)
+		return
+	case err != nil && stream == nil:
+		c.respondReadAllError(w, r, err)
+		return
+	case err != nil:
+		c.recordAccessError(r, err)
+		c.errorf(r, "Streaming %s: failed after %d entities: %v", c.repository().EntityName(), count, err)
+		return
+	}
+	if stream == nil {
+		start()
+	}
+	if err := stream.Close(); err != nil {
+		c.errorf(r, "Streaming %s: %v", c.repository().EntityName(), err)
+		return
+	}
+	if flusher != nil {
+		flusher.Flush()
+	}
+}
+
+// NewStream returns a RenderStream that writes the collection as a JSON array
+func (JSONRenderer) NewStream(w io.Writer) RenderStream {
+	return &jsonStream{w: w}
+}
+
+type jsonStream struct {
+	w     io.Writer
+	count int
+}
+
+func (s *jsonStream) Write(entity interface{}) error {
+	buf, err := json.Marshal(entity)
+	if err != nil {
+		return err
+	}
+	sep := ","
+	if s.count == 0 {
+		sep = "["
+	}
+	s.count++
+	if _, err := io.WriteString(s.w, sep); err != nil {
+		return err
+	}
+	_, err = s.w.Write(buf)
+	return err
+}
+
+func (s *jsonStream) Close() error {
+	end := "]"
+	if s.count == 0 {
+		end = "[]"
+	}
+	_, err := io.WriteString(s.w, end)
+	return err
+}
+
+/*
+NDJSONRenderer renders payloads as newline delimited JSON. Collections are rendered with one entity per line, and
+other payloads are rendered in a single line
+*/
+type NDJSONRenderer struct{}
+
+// ContentType returns application/x-ndjson
+func (NDJSONRenderer) ContentType() string { return "application/x-ndjson" }
+
+// Render writes the payload as NDJSON
+func (r NDJSONRenderer) Render(w io.Writer, payload interface{}) error {
+	buf, err := json.Marshal(payload)
+	if err != nil {
+		return err
+	}
+	var items []json.RawMessage
+	if err := json.Unmarshal(buf, &items); err != nil {
+		items = []json.RawMessage{buf}
+	}
+	stream := r.NewStream(w)
+	for _, item := range items {
+		if err := stream.Write(item); err != nil {
+			return err
+		}
+	}
+	return stream.Close()
+}
+
+// NewStream returns a RenderStream that writes one entity per line
+func (NDJSONRenderer) NewStream(w io.Writer) RenderStream {
+	return ndjsonStream{json.NewEncoder(w)}
+}
+
+type ndjsonStream struct {
+	encoder *json.Encoder
+}
+
+func (s ndjsonStream) Write(entity interface{}) error {
+	return s.encoder.Encode(entity)
+}
+
+func (s ndjsonStream) Close() error {
+	return nil
+}
@@ -0,0 +1,141 @@
+package rest_test
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/deluan/rest"
+	"github.com/deluan/rest/examples"
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+// streamingRepository adds rest.Streamable to the SampleRepository, optionally failing after a number of entities
+type streamingRepository struct {
+	*examples.PersistableSampleRepository
+	failAfter int
+	onYield   func()
+	yielded   int
+}
+
+func (r *streamingRepository) ReadAllIter(yield func(entity interface{}) error, options ...rest.QueryOptions) error {
+	all, err := r.ReadAll(options...)
+	if err != nil {
+		return err
+	}
+	for _, entity := range all.([]examples.SampleModel) {
+		if r.failAfter >= 0 && r.yielded == r.failAfter {
+			return errors.New("connection lost")
+		}
+		if err := yield(entity); err != nil {
+			return err
+		}
+		r.yielded++
+		if r.onYield != nil {
+			r.onYield()
+		}
+	}
+	return nil
+}
+
+func TestController_GetAllStreaming(t *testing.T) {
+	Convey("Given a streamable repository with two items", t, func() {
+		repo := &streamingRepository{PersistableSampleRepository: examples.NewPersistableSampleRepository(nil), failAfter: -1}
+		joe := aRecord("Joe", 30)
+		idJoe, _ := repo.Save(&joe)
+		cecilia := aRecord("Cecilia", 22)
+		idCecilia, _ := repo.Save(&cecilia)
+		handler := rest.GetAll(func(ctx context.Context) rest.Repository { return repo }, logger)
+
+		Convey("When I call GetAll", func() {
+			req, res := createRequestResponse("GET", "/sample?_sort=age", nil)
+			handler(res, req)
+
+			Convey("It streams the records as a JSON array", func() {
+				So(res.Code, ShouldEqual, 200)
+				So(res.Header().Get("Content-Type"), ShouldEqual, "application/json")
+				So(res.Body.String(), ShouldEqual,
+					`[{"ID":"`+idCecilia+`","Name":"Cecilia","Age":22},{"ID":"`+idJoe+`","Name":"Joe","Age":30}]`)
+				So(res.Flushed, ShouldBeTrue)
+			})
+
+			Convey("It returns 2 in the X-Total-Count header", func() {
+				So(res.Header().Get("X-Total-Count"), ShouldEqual, "2")
+			})
+
+			Convey("It does not return an ETag", func() {
+				So(res.Header().Get("ETag"), ShouldBeEmpty)
+			})
+		})
+
+		Convey("When I call GetAll accepting NDJSON", func() {
+			req, res := createRequestResponse("GET", "/sample?_sort=age", nil)
+			req.Header.Set("Accept", "application/x-ndjson")
+			handler(res, req)
+
+			Convey("It streams one record per line", func() {
+				So(res.Code, ShouldEqual, 200)
+				So(res.Header().Get("Content-Type"), ShouldEqual, "application/x-ndjson")
+				So(res.Body.String(), ShouldEqual,
+					`{"ID":"`+idCecilia+`","Name":"Cecilia","Age":22}`+"\n"+`{"ID":"`+idJoe+`","Name":"Joe","Age":30}`+"\n")
+			})
+		})
+
+		Convey("When I call GetAll accepting a format that cannot be streamed", func() {
+			req, res := createRequestResponse("GET", "/sample", nil)
+			req.Header.Set("Accept", "text/csv")
+			handler(res, req)
+
+			Convey("It does not stream the records", func() {
+				So(res.Code, ShouldEqual, 200)
+				So(strings.Split(res.Body.String(), "\n"), ShouldHaveLength, 4)
+				So(res.Flushed, ShouldBeFalse)
+			})
+		})
+
+		Convey("When the repository fails before the first record", func() {
+			repo.failAfter = 0
+			req, res := createRequestResponse("GET", "/sample", nil)
+			handler(res, req)
+
+			Convey("It returns 500 http status", func() {
+				So(res.Code, ShouldEqual, 500)
+				So(res.Body.String(), ShouldContainSubstring, "connection lost")
+			})
+		})
+
+		Convey("When the repository returns a ErrPermissionDenied", func() {
+			repo.Error = rest.ErrPermissionDenied
+			req, res := createRequestResponse("GET", "/sample", nil)
+			handler(res, req)
+
+			Convey("It returns 403 http status", func() {
+				So(res.Code, ShouldEqual, 403)
+			})
+		})
+
+		Convey("When the repository fails after the first record", func() {
+			repo.failAfter = 1
+			req, res := createRequestResponse("GET", "/sample?_sort=age", nil)
+			handler(res, req)
+
+			Convey("It interrupts the response", func() {
+				So(res.Code, ShouldEqual, 200)
+				So(res.Body.String(), ShouldEqual, `[{"ID":"`+idCecilia+`","Name":"Cecilia","Age":22}`)
+			})
+		})
+
+		Convey("When the request is cancelled", func() {
+			ctx, cancel := context.WithCancel(context.Background())
+			repo.onYield = cancel
+			req, res := createRequestResponse("GET", "/sample", nil)
+			handler(res, req.WithContext(ctx))
+
+			Convey("It stops the iteration", func() {
+				So(repo.yielded, ShouldEqual, 1)
+				So(res.Body.String(), ShouldNotEndWith, "]")
+			})
+		})
+	})
+}
@@ -0,0 +1,175 @@
+//go:build go1.21
+// +build go1.21
+
+package rest
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+)
+
+/*
+TypedRepository is a type-safe version of the RepositoryV2 interface, for entities of type T. Use it with the Typed*
+handler functions (ex: TypedGet), or wrap it with FromTyped to use it with other functions of this package. The
+optional interfaces (CursorPaginatedV2, StreamableV2, BulkPersistableV2, or their versions without context) can also
+be implemented by TypedRepository implementations. Requires Go 1.21 or later: as go.mod declares an older language
+version, only these versions enable generics in files restricted by a build tag.
+*/
+type TypedRepository[T any] interface {
+	// Returns the number of entities that matches the criteria specified by the options
+	Count(ctx context.Context, options ...QueryOptions) (int64, error)
+
+	// Returns the entity identified by id
+	Read(ctx context.Context, id string) (T, error)
+
+	// Returns the entities that matches the criteria specified by the options
+	ReadAll(ctx context.Context, options ...QueryOptions) ([]T, error)
+
+	// Return the entity name (used for logs and messages)
+	EntityName() string
+}
+
+/*
+TypedPersistable is a type-safe version of the PersistableV2 interface. It must be implemented by TypedRepository
+implementations to allow the POST, PUT, PATCH and DELETE methods
+*/
+type TypedPersistable[T any] interface {
+	// Adds the entity to the repository and returns the newly created id
+	Save(ctx context.Context, entity *T) (string, error)
+
+	// Updates the entity identified by id. Optionally select the fields to be updated
+	Update(ctx context.Context, id string, entity *T, cols ...string) error
+
+	// Delete the entity identified by id
+	Delete(ctx context.Context, id string) error
+}
+
+// AdaptTyped wraps a TypedRepository as a RepositoryV2. Its NewInstance returns a new *T
+func AdaptTyped[T any](repo TypedRepository[T]) RepositoryV2 {
+	if rp, ok := repo.(TypedPersistable[T]); ok {
+		return typedPersistableAdapter[T]{typedAdapter: typedAdapter[T]{repo}, rp: rp}
+	}
+	return typedAdapter[T]{repo}
+}
+
+// FromTyped returns a RepositoryConstructor for a TypedRepository, so it can be used with the handler functions
+func FromTyped[T any](repo TypedRepository[T]) RepositoryConstructor {
+	return FromV2(AdaptTyped(repo))
+}
+
+/*
+TypedGet handles the GET verb for individual items. Should be mapped to:
+GET /thing/:id
+*/
+func TypedGet[T any](repo TypedRepository[T], logger ...Logger) http.HandlerFunc {
+	return Get(FromTyped(repo), logger...)
+}
+
+/*
+TypedGetAll handles the GET verb for the full collection. Should be mapped to:
+GET /thing
+*/
+func TypedGetAll[T any](repo TypedRepository[T], logger ...Logger) http.HandlerFunc {
+	return GetAll(FromTyped(repo), logger...)
+}
+
+/*
+TypedPost handles the POST verb. Should be mapped to:
+POST /thing
+*/
+func TypedPost[T any](repo TypedRepository[T], logger ...Logger) http.HandlerFunc {
+	return Post(FromTyped(repo), logger...)
+}
+
+/*
+TypedPut handles the PUT verb. Should be mapped to:
+PUT /thing/:id
+*/
+func TypedPut[T any](repo TypedRepository[T], logger ...Logger) http.HandlerFunc {
+	return Put(FromTyped(repo), logger...)
+}
+
+/*
+TypedPatch handles the PATCH verb. Should be mapped to:
+PATCH /thing/:id
+*/
+func TypedPatch[T any](repo TypedRepository[T], logger ...Logger) http.HandlerFunc {
+	return Patch(FromTyped(repo), logger...)
+}
+
+/*
+TypedDelete handles the DELETE verb. Should be mapped to:
+DELETE /thing/:id
+*/
+func TypedDelete[T any](repo TypedRepository[T], logger ...Logger) http.HandlerFunc {
+	return Delete(FromTyped(repo), logger...)
+}
+
+// typedAdapter implements RepositoryV2 for a TypedRepository
+type typedAdapter[T any] struct {
+	repo TypedRepository[T]
+}
+
+func (a typedAdapter[T]) underlying() interface{} { return a.repo }
+
+func (a typedAdapter[T]) Count(ctx context.Context, options ...QueryOptions) (int64, error) {
+	return a.repo.Count(ctx, options...)
+}
+
+func (a typedAdapter[T]) Read(ctx context.Context, id string) (interface{}, error) {
+	entity, err := a.repo.Read(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	return entity, nil
+}
+
+func (a typedAdapter[T]) ReadAll(ctx context.Context, options ...QueryOptions) (interface{}, error) {
+	entities, err := a.repo.ReadAll(ctx, options...)
+	if err != nil {
+		return nil, err
+	}
+	if entities == nil {
+		entities = []T{}
+	}
+	return entities, nil
+}
+
+func (a typedAdapter[T]) EntityName() string { return a.repo.EntityName() }
+
+func (a typedAdapter[T]) NewInstance() interface{} { return new(T) }
+
+type typedPersistableAdapter[T any] struct {
+	typedAdapter[T]
+	rp TypedPersistable[T]
+}
+
+func (a typedPersistableAdapter[T]) Save(ctx context.Context, entity interface{}) (string, error) {
+	e, err := typedEntity[T](entity)
+	if err != nil {
+		return "", err
+	}
+	return a.rp.Save(ctx, e)
+}
+
+func (a typedPersistableAdapter[T]) Update(ctx context.Context, id string, entity interface{}, cols ...string) error {
+	e, err := typedEntity[T](entity)
+	if err != nil {
+		return err
+	}
+	return a.rp.Update(ctx, id, e, cols...)
+}
+
+func (a typedPersistableAdapter[T]) Delete(ctx context.Context, id string) error {
+	return a.rp.Delete(ctx, id)
+}
+
+// typedEntity converts the entities received from the controller (created with NewInstance) back to *T
+func typedEntity[T any](entity interface{}) (*T, error) {
+	e, ok := entity.(*T)
+	if !ok {
+		return nil, fmt.Errorf("invalid entity type %T, expected %T", entity, e)
+	}
+	return e, nil
+}
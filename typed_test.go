@@ -0,0 +1,140 @@
+//go:build go1.21
+// +build go1.21
+
+package rest_test
+
+import (
+	"context"
+	"encoding/json"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/deluan/rest"
+	"github.com/deluan/rest/examples"
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+// typedRepository is a TypedRepository for SampleModels, stored in memory
+type typedRepository struct {
+	data map[string]examples.SampleModel
+}
+
+func (r *typedRepository) Count(ctx context.Context, options ...rest.QueryOptions) (int64, error) {
+	return int64(len(r.data)), nil
+}
+
+func (r *typedRepository) Read(ctx context.Context, id string) (examples.SampleModel, error) {
+	if m, ok := r.data[id]; ok {
+		return m, nil
+	}
+	return examples.SampleModel{}, rest.ErrNotFound
+}
+
+func (r *typedRepository) ReadAll(ctx context.Context, options ...rest.QueryOptions) ([]examples.SampleModel, error) {
+	var all []examples.SampleModel
+	for _, m := range r.data {
+		all = append(all, m)
+	}
+	sort.Slice(all, func(i, j int) bool { return all[i].ID < all[j].ID })
+	return all, nil
+}
+
+func (r *typedRepository) EntityName() string { return "sample" }
+
+func (r *typedRepository) Save(ctx context.Context, entity *examples.SampleModel) (string, error) {
+	r.data[entity.ID] = *entity
+	return entity.ID, nil
+}
+
+func (r *typedRepository) Update(ctx context.Context, id string, entity *examples.SampleModel, cols ...string) error {
+	entity.ID = id
+	r.data[id] = *entity
+	return nil
+}
+
+func (r *typedRepository) Delete(ctx context.Context, id string) error {
+	delete(r.data, id)
+	return nil
+}
+
+// readOnlyTypedRepository hides the TypedPersistable methods of the typedRepository
+type readOnlyTypedRepository struct {
+	rest.TypedRepository[examples.SampleModel]
+}
+
+func TestTypedHandlers(t *testing.T) {
+	Convey("Given a TypedRepository", t, func() {
+		repo := &typedRepository{data: map[string]examples.SampleModel{}}
+
+		Convey("When I call TypedGetAll on an empty repository", func() {
+			req, res := createRequestResponse("GET", "/sample", nil)
+			rest.TypedGetAll[examples.SampleModel](repo, logger)(res, req)
+
+			Convey("It returns an empty collection", func() {
+				So(res.Code, ShouldEqual, 200)
+				So(res.Body.String(), ShouldEqual, "[]")
+			})
+		})
+
+		Convey("When I call TypedPost", func() {
+			req, res := createRequestResponse("POST", "/sample", aRecordReader("1", "Joe", 30))
+			rest.TypedPost[examples.SampleModel](repo, logger)(res, req)
+
+			Convey("It saves the record", func() {
+				So(res.Code, ShouldEqual, 200)
+				So(repo.data["1"], ShouldResemble, examples.SampleModel{ID: "1", Name: "Joe", Age: 30})
+			})
+
+			Convey("And I call TypedGet", func() {
+				req, res := createRequestResponse("GET", "/sample?:id=1", nil)
+				rest.TypedGet[examples.SampleModel](repo, logger)(res, req)
+
+				Convey("It returns the record", func() {
+					var m examples.SampleModel
+					_ = json.Unmarshal(res.Body.Bytes(), &m)
+					So(res.Code, ShouldEqual, 200)
+					So(m, ShouldResemble, examples.SampleModel{ID: "1", Name: "Joe", Age: 30})
+				})
+			})
+
+			Convey("And I call TypedPatch", func() {
+				req, res := createRequestResponse("PATCH", "/sample?:id=1", strings.NewReader(`{"Age":31}`))
+				rest.TypedPatch[examples.SampleModel](repo, logger)(res, req)
+
+				Convey("It updates the record", func() {
+					So(res.Code, ShouldEqual, 200)
+					So(repo.data["1"].Age, ShouldEqual, 31)
+				})
+			})
+
+			Convey("And I call TypedDelete", func() {
+				req, res := createRequestResponse("DELETE", "/sample?:id=1", nil)
+				rest.TypedDelete[examples.SampleModel](repo, logger)(res, req)
+
+				Convey("It deletes the record", func() {
+					So(res.Code, ShouldEqual, 200)
+					So(repo.data, ShouldBeEmpty)
+				})
+			})
+		})
+
+		Convey("When I call TypedPut on a read-only TypedRepository", func() {
+			readOnly := readOnlyTypedRepository{repo}
+			req, res := createRequestResponse("PUT", "/sample?:id=1", aRecordReader("1", "Joe", 30))
+			rest.TypedPut[examples.SampleModel](readOnly, logger)(res, req)
+
+			Convey("It returns 405 http status", func() {
+				So(res.Code, ShouldEqual, 405)
+			})
+		})
+
+		Convey("When I adapt it to RepositoryV2", func() {
+			adapted := rest.AdaptTyped[examples.SampleModel](repo)
+
+			Convey("NewInstance returns a pointer to a new entity", func() {
+				So(adapted.NewInstance(), ShouldResemble, &examples.SampleModel{})
+			})
+		})
+	})
+}